@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+)
+
+// fieldsKey is the context key of the fields carried by the context, see WithFields.
+type fieldsKey struct{}
+
+// ctxField represents a field carried by the context.
+type ctxField struct {
+	key   string
+	value any
+}
+
+// WithFields returns a copy of the context that carries the key and values,
+// logged in every entry logged with the context or with the contexts derived from it,
+// alongside the CTXFields of the Configuration.
+//
+// The fields accumulate down the call stack: the fields already carried by the context are kept,
+// and a key that is added again has its value replaced.
+//
+// Ex.:
+//
+//	ctx = logger.WithFields(ctx, "order_id", order.ID, "store_id", order.StoreID)
+//
+//	// will log: {"message": "order paid", "order_id": "...", "store_id": "...", "payment_id": "..."}
+//	logger.Infow(ctx, "order paid", "payment_id", payment.ID)
+func WithFields(ctx context.Context, keysAndValues ...any) context.Context {
+	if len(keysAndValues) == 0 {
+		return ctx
+	}
+
+	parent := contextFields(ctx)
+	fields := make([]ctxField, len(parent), len(parent)+len(keysAndValues)/2)
+	copy(fields, parent)
+
+	for i := 0; i < len(keysAndValues); i += 2 {
+		field := ctxField{key: fmt.Sprint(keysAndValues[i])}
+		if i+1 < len(keysAndValues) {
+			field.value = keysAndValues[i+1]
+		}
+
+		fields = setField(fields, field)
+	}
+
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// Fields returns the key and values carried by the context, see WithFields.
+func Fields(ctx context.Context) []any {
+	fields := contextFields(ctx)
+
+	keysAndValues := make([]any, 0, len(fields)*2)
+	for _, f := range fields {
+		keysAndValues = append(keysAndValues, f.key, f.value)
+	}
+
+	return keysAndValues
+}
+
+// contextFields returns the fields carried by the context.
+func contextFields(ctx context.Context) []ctxField {
+	if ctx == nil {
+		return nil
+	}
+
+	fields, _ := ctx.Value(fieldsKey{}).([]ctxField)
+	return fields
+}
+
+// setField replaces the value of the field with the same key, or appends the field.
+func setField(fields []ctxField, field ctxField) []ctxField {
+	for i := range fields {
+		if fields[i].key == field.key {
+			fields[i] = field
+			return fields
+		}
+	}
+
+	return append(fields, field)
+}
@@ -0,0 +1,235 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// errorKey is the key of the field of Err
+	errorKey = "error"
+
+	// maxCauses limits the causes of an error, in case of a cycle in the chain
+	maxCauses = 32
+	// maxStackDepth limits the frames of the stack traces
+	maxStackDepth = 64
+)
+
+// skippedStackPrefixes are the functions of the frames on top of the stack traces that are skipped,
+// so the traces start at the call of the logger.
+var skippedStackPrefixes = []string{
+	"github.com/delivery-much/dm-go/logger.",
+	"go.uber.org/zap.",
+	"go.uber.org/zap/",
+}
+
+// Stacker is implemented by the errors that carry the stack trace of where they were created, see WithStack.
+type Stacker interface {
+	Stack() string
+}
+
+// stackError represents an error with the stack trace of where it was wrapped, see WithStack.
+type stackError struct {
+	err   error
+	stack string
+}
+
+func (e *stackError) Error() string { return e.err.Error() }
+func (e *stackError) Unwrap() error { return e.err }
+func (e *stackError) Stack() string { return e.stack }
+
+// WithStack returns the error with the stack trace of the call, logged by Err.
+// It returns nil if the error is nil, and the error itself if it already carries a stack trace.
+//
+// Ex.:
+//
+//	order, err := repository.Get(ctx, id)
+//	if err != nil {
+//		return logger.WithStack(err)
+//	}
+func WithStack(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var s Stacker
+	if errors.As(err, &s) {
+		return err
+	}
+
+	return &stackError{err: err, stack: captureStack(2)}
+}
+
+// Err returns the field of the error, logged under the "error" key with its message, its type,
+// the causes of its errors.Unwrap and errors.Join chain, and the stack trace of the first error of the chain that has one, see Stacker.
+//
+// Ex.:
+//
+//	// will log: {"message": "failed to create order", "error": {"message": "...", "type": "*net.OpError", "causes": [...], "stack": "..."}}
+//	logger.Errorw(ctx, "failed to create order", logger.Err(err))
+func Err(err error) Field {
+	if err == nil {
+		return zap.Skip()
+	}
+
+	return zap.Object(errorKey, errorObject{err: err})
+}
+
+// errorObject represents the error logged by Err.
+type errorObject struct {
+	err error
+}
+
+func (e errorObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("message", e.err.Error())
+	enc.AddString("type", fmt.Sprintf("%T", withoutStack(e.err)))
+
+	if causes := errorCauses(e.err); len(causes) > 0 {
+		enc.AddArray("causes", causes)
+	}
+
+	var s Stacker
+	if errors.As(e.err, &s) {
+		enc.AddString("stack", s.Stack())
+	}
+
+	return nil
+}
+
+// withoutStack returns the error wrapped by WithStack, or the error itself if it was not wrapped.
+func withoutStack(err error) error {
+	for {
+		se, ok := err.(*stackError)
+		if !ok {
+			return err
+		}
+		err = se.err
+	}
+}
+
+// causeArray represents the causes of an error.
+type causeArray []error
+
+func (c causeArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, err := range c {
+		enc.AppendObject(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("message", err.Error())
+			enc.AddString("type", fmt.Sprintf("%T", err))
+			return nil
+		}))
+	}
+	return nil
+}
+
+// errorCauses returns the errors wrapped by the error, depth first, ignoring the wrappers of WithStack.
+func errorCauses(err error) causeArray {
+	var causes causeArray
+
+	var walk func(err error)
+	walk = func(err error) {
+		var wrapped []error
+		switch e := err.(type) {
+		case interface{ Unwrap() []error }:
+			wrapped = e.Unwrap()
+		case interface{ Unwrap() error }:
+			wrapped = []error{e.Unwrap()}
+		}
+
+		for _, w := range wrapped {
+			if w == nil || len(causes) >= maxCauses {
+				continue
+			}
+			if _, ok := w.(*stackError); !ok {
+				causes = append(causes, w)
+			}
+			walk(w)
+		}
+	}
+	walk(withoutStack(err))
+
+	return causes
+}
+
+// captureStack returns the stack trace of the caller, skipping the given number of frames
+// and the frames of the logger on top of it, in the format of the stack traces of zap.
+func captureStack(skip int) string {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip+1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var b strings.Builder
+	top := true
+	for {
+		frame, more := frames.Next()
+		if top && hasSkippedPrefix(frame.Function) {
+			if !more {
+				break
+			}
+			continue
+		}
+		top = false
+
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+
+		if !more {
+			break
+		}
+	}
+
+	return b.String()
+}
+
+// hasSkippedPrefix returns if the function is one of the functions skipped on top of the stack traces.
+func hasSkippedPrefix(function string) bool {
+	for _, prefix := range skippedStackPrefixes {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// stackCore represents a zapcore.Core that adds the stack trace of the call to the entries from a level on.
+// The stack trace is added when the entry is checked, so it is written by every core of a tee.
+type stackCore struct {
+	zapcore.Core
+	level zapcore.Level
+}
+
+func (c *stackCore) With(fields []zapcore.Field) zapcore.Core {
+	return &stackCore{
+		Core:  c.Core.With(fields),
+		level: c.level,
+	}
+}
+
+func (c *stackCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level >= c.level && ent.Stack == "" && c.Enabled(ent.Level) {
+		ent.Stack = captureStack(1)
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// stacktraceLevel returns the level from which the stack traces are added to the entries,
+// and false if they are disabled.
+func stacktraceLevel(config Configuration) (zapcore.Level, bool, error) {
+	if config.DisableStacktrace {
+		return zapcore.InfoLevel, false, nil
+	}
+	if config.StacktraceLevel != "" {
+		level, err := parseLevel(config.StacktraceLevel)
+		return level, err == nil, err
+	}
+	if config.IsJSON {
+		return zapcore.ErrorLevel, true, nil
+	}
+	return zapcore.WarnLevel, true, nil
+}
@@ -0,0 +1,150 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+// newBufferedLogger returns a JSON logger with the given configuration that writes to the returned buffer.
+func newBufferedLogger(t *testing.T, config Configuration) (Logger, *bytes.Buffer) {
+	var out bytes.Buffer
+	config.Outputs = []Output{{Writer: &out, IsJSON: true}}
+
+	l, err := New(config)
+	assert.Nil(t, err)
+	return l, &out
+}
+
+// decodeEntry returns the entry written to the buffer.
+func decodeEntry(t *testing.T, out *bytes.Buffer) map[string]any {
+	var entry map[string]any
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &entry))
+	return entry
+}
+
+func TestErr(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should log the message, type and causes of the error", func(t *testing.T) {
+		l, logs := newObservedLogger(nil)
+		_, cause := os.Open("/does/not/exist")
+		err := fmt.Errorf("failed to load config: %w", cause)
+
+		l.Errorw(ctx, "startup failed", Err(err))
+
+		logged := logs.All()[0].ContextMap()["error"].(map[string]any)
+		assert.Equal(t, err.Error(), logged["message"])
+		assert.Equal(t, "*fmt.wrapError", logged["type"])
+		causes := logged["causes"].([]any)
+		assert.Len(t, causes, 2)
+		assert.Equal(t, map[string]any{"message": cause.Error(), "type": "*fs.PathError"}, causes[0])
+		assert.Equal(t, "syscall.Errno", causes[1].(map[string]any)["type"])
+		assert.NotContains(t, logged, "stack")
+	})
+
+	t.Run("Should log the causes of joined errors depth first", func(t *testing.T) {
+		l, logs := newObservedLogger(nil)
+		first := errors.New("first")
+		second := fmt.Errorf("second: %w", errors.New("nested"))
+
+		l.Errorw(ctx, "batch failed", Err(errors.Join(first, second)))
+
+		logged := logs.All()[0].ContextMap()["error"].(map[string]any)
+		causes := logged["causes"].([]any)
+		assert.Len(t, causes, 3)
+		assert.Equal(t, "first", causes[0].(map[string]any)["message"])
+		assert.Equal(t, "second: nested", causes[1].(map[string]any)["message"])
+		assert.Equal(t, "nested", causes[2].(map[string]any)["message"])
+	})
+
+	t.Run("Should log the stack of the errors with stack", func(t *testing.T) {
+		l, logs := newObservedLogger(nil)
+		cause := errors.New("timeout")
+		err := fmt.Errorf("failed to create order: %w", WithStack(cause))
+
+		l.Errorw(ctx, "request failed", Err(err))
+
+		logged := logs.All()[0].ContextMap()["error"].(map[string]any)
+		assert.Contains(t, logged["stack"], "testing.tRunner")
+		assert.Equal(t, []any{
+			map[string]any{"message": "timeout", "type": "*errors.errorString"},
+		}, logged["causes"])
+	})
+
+	t.Run("Should log the type of the error wrapped by WithStack", func(t *testing.T) {
+		l, logs := newObservedLogger(nil)
+
+		l.Errorw(ctx, "request failed", Err(WithStack(errors.New("timeout"))))
+
+		logged := logs.All()[0].ContextMap()["error"].(map[string]any)
+		assert.Equal(t, "*errors.errorString", logged["type"])
+		assert.NotContains(t, logged, "causes")
+	})
+
+	t.Run("Should not wrap an error that already has a stack", func(t *testing.T) {
+		err := WithStack(errors.New("timeout"))
+
+		assert.Same(t, err, WithStack(err))
+		assert.Nil(t, WithStack(nil))
+	})
+
+	t.Run("Should skip a nil error", func(t *testing.T) {
+		l, logs := newObservedLogger(nil)
+
+		l.Errorw(ctx, "request failed", Err(nil))
+
+		assert.NotContains(t, logs.All()[0].ContextMap(), "error")
+	})
+}
+
+func TestStacktrace(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should add the stack of the call to the error entries in JSON mode", func(t *testing.T) {
+		l, out := newBufferedLogger(t, Configuration{IsJSON: true})
+
+		l.Error(ctx, "order failed")
+
+		stack := decodeEntry(t, out)["stack"].(string)
+		assert.Contains(t, stack, "testing.tRunner")
+		assert.NotContains(t, stack, "go.uber.org/zap")
+		assert.NotContains(t, stack, "(*zapLogger)")
+	})
+
+	t.Run("Should not add the stack below the stacktrace level", func(t *testing.T) {
+		l, out := newBufferedLogger(t, Configuration{IsJSON: true})
+
+		l.Warn(ctx, "order slow")
+
+		assert.NotContains(t, decodeEntry(t, out), "stack")
+	})
+
+	t.Run("Should add the stack from the configured level", func(t *testing.T) {
+		l, out := newBufferedLogger(t, Configuration{IsJSON: true, StacktraceLevel: WARN})
+
+		l.Warn(ctx, "order slow")
+
+		assert.Contains(t, decodeEntry(t, out), "stack")
+	})
+
+	t.Run("Should not add the stack when disabled", func(t *testing.T) {
+		l, out := newBufferedLogger(t, Configuration{IsJSON: true, DisableStacktrace: true})
+
+		l.Error(ctx, "order failed")
+
+		assert.NotContains(t, decodeEntry(t, out), "stack")
+	})
+
+	t.Run("Should fail with an unknown stacktrace level", func(t *testing.T) {
+		_, err := New(Configuration{StacktraceLevel: "verbose"})
+
+		assert.Error(t, err)
+	})
+}
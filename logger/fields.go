@@ -0,0 +1,215 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/net/context"
+)
+
+// Field represents a typed key and value, logged by the XFields functions without boxing the value in an interface.
+type Field = zapcore.Field
+
+// ObjectMarshaler is implemented by the types logged by Object.
+type ObjectMarshaler = zapcore.ObjectMarshaler
+
+// ObjectEncoder encodes the fields of an ObjectMarshaler.
+type ObjectEncoder = zapcore.ObjectEncoder
+
+// String returns the field of a string.
+func String(key, value string) Field { return zap.String(key, value) }
+
+// Int returns the field of an int.
+func Int(key string, value int) Field { return zap.Int(key, value) }
+
+// Int64 returns the field of an int64.
+func Int64(key string, value int64) Field { return zap.Int64(key, value) }
+
+// Float64 returns the field of a float64.
+func Float64(key string, value float64) Field { return zap.Float64(key, value) }
+
+// Bool returns the field of a bool.
+func Bool(key string, value bool) Field { return zap.Bool(key, value) }
+
+// Duration returns the field of a duration, logged in seconds in JSON mode.
+func Duration(key string, value time.Duration) Field { return zap.Duration(key, value) }
+
+// Time returns the field of a time.
+func Time(key string, value time.Time) Field { return zap.Time(key, value) }
+
+// Object returns the field of a value that encodes its own fields, without reflection.
+//
+// Ex.:
+//
+//	func (o Order) MarshalLogObject(enc logger.ObjectEncoder) error {
+//		enc.AddString("id", o.ID)
+//		enc.AddInt("items", len(o.Items))
+//		return nil
+//	}
+//
+//	logger.InfoFields(ctx, "order created", logger.Object("order", order))
+func Object(key string, value ObjectMarshaler) Field { return zap.Object(key, value) }
+
+// Any returns the field of any value, choosing the typed field of its type, or encoding it with reflection when there is none.
+func Any(key string, value any) Field { return zap.Any(key, value) }
+
+// ctxKey represents a configured context field, see the CTXFields of the Configuration.
+type ctxKey struct {
+	key   any
+	field string
+}
+
+// newCTXKeys returns the context fields of the map, sorted by field so they are logged in a stable order.
+func newCTXKeys(ctxFields map[any]string) []ctxKey {
+	keys := make([]ctxKey, 0, len(ctxFields))
+	for key, field := range ctxFields {
+		keys = append(keys, ctxKey{key: key, field: field})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].field != keys[j].field {
+			return keys[i].field < keys[j].field
+		}
+		return fmt.Sprint(keys[i].key) < fmt.Sprint(keys[j].key)
+	})
+
+	return keys
+}
+
+// fieldsPool reuses the slices of fields of the entries logged by the XFields functions.
+var fieldsPool = sync.Pool{
+	New: func() any {
+		fields := make([]Field, 0, 16)
+		return &fields
+	},
+}
+
+// setZapField replaces the field with the same key, or appends the field.
+func setZapField(fields []Field, field Field) []Field {
+	for i := range fields {
+		if fields[i].Key == field.Key {
+			fields[i] = field
+			return fields
+		}
+	}
+
+	return append(fields, field)
+}
+
+// appendCTXFields appends the fields of the context to the fields, in the same order and with the same replacements of addCTXFields.
+func (l *zapLogger) appendCTXFields(ctx context.Context, fields []Field) []Field {
+	for _, k := range l.ctxFields {
+		if val := ctx.Value(k.key); val != nil {
+			fields = setZapField(fields, zap.Any(k.field, val))
+		}
+	}
+	if trace, ok := l.traceFields.fields(ctx); ok {
+		for _, f := range trace {
+			fields = setZapField(fields, f)
+		}
+	}
+	for _, f := range contextFields(ctx) {
+		fields = setZapField(fields, zap.Any(f.key, f.value))
+	}
+
+	return fields
+}
+
+// log logs the entry with the fields of the context and the given fields through the structured logger,
+// checking the level before any field is collected.
+func (l *zapLogger) log(ctx context.Context, level zapcore.Level, msg string, fields []Field) {
+	if ce := l.check(ctx, level, msg); ce != nil {
+		l.write(ctx, ce, fields)
+	}
+}
+
+// check returns the entry to be written, or nil if it is not enabled or it is sampled out.
+func (l *zapLogger) check(ctx context.Context, level zapcore.Level, msg string) *zapcore.CheckedEntry {
+	logger := l.logger
+	if l.unsampledLogger != nil && ctx != nil && neverSample(ctx) {
+		logger = l.unsampledLogger
+	}
+	if logger == nil {
+		return nil
+	}
+
+	return logger.Check(level, msg)
+}
+
+// write writes the checked entry with the fields of the context and the given fields.
+func (l *zapLogger) write(ctx context.Context, ce *zapcore.CheckedEntry, fields []Field) {
+	// the fields are copied to a pooled slice, so the variadic slice of the caller does not escape to the heap
+	buf := fieldsPool.Get().(*[]Field)
+	all := (*buf)[:0]
+	if ctx != nil {
+		all = l.appendCTXFields(ctx, all)
+	}
+	all = append(all, fields...)
+	ce.Write(all...)
+
+	// the values are cleared so the pool does not retain them
+	clear(all)
+	*buf = all[:0]
+	fieldsPool.Put(buf)
+}
+
+// DebugFields logs a debug message with typed fields, see Field.
+func DebugFields(ctx context.Context, msg string, fields ...Field) {
+	Default().DebugFields(ctx, msg, fields...)
+}
+
+// InfoFields logs an info message with typed fields, see Field.
+//
+// Ex.:
+//
+//	logger.InfoFields(ctx, "order created", logger.String("order_id", order.ID), logger.Duration("elapsed", elapsed))
+func InfoFields(ctx context.Context, msg string, fields ...Field) {
+	Default().InfoFields(ctx, msg, fields...)
+}
+
+// WarnFields logs a warn message with typed fields, see Field.
+func WarnFields(ctx context.Context, msg string, fields ...Field) {
+	Default().WarnFields(ctx, msg, fields...)
+}
+
+// ErrorFields logs an error message with typed fields, see Field and Err.
+func ErrorFields(ctx context.Context, msg string, fields ...Field) {
+	Default().ErrorFields(ctx, msg, fields...)
+}
+
+// FatalFields logs a fatal message with typed fields, see Field. The system shuts down after logging the message.
+func FatalFields(ctx context.Context, msg string, fields ...Field) {
+	Default().FatalFields(ctx, msg, fields...)
+}
+
+// PanicFields logs a message with typed fields, see Field, then panics.
+func PanicFields(ctx context.Context, msg string, fields ...Field) {
+	Default().PanicFields(ctx, msg, fields...)
+}
+
+func (l *ctxLogger) DebugFields(ctx context.Context, msg string, fields ...Field) {
+	l.zl.log(ctx, zapcore.DebugLevel, msg, fields)
+}
+
+func (l *ctxLogger) InfoFields(ctx context.Context, msg string, fields ...Field) {
+	l.zl.log(ctx, zapcore.InfoLevel, msg, fields)
+}
+
+func (l *ctxLogger) WarnFields(ctx context.Context, msg string, fields ...Field) {
+	l.zl.log(ctx, zapcore.WarnLevel, msg, fields)
+}
+
+func (l *ctxLogger) ErrorFields(ctx context.Context, msg string, fields ...Field) {
+	l.zl.log(ctx, zapcore.ErrorLevel, msg, fields)
+}
+
+func (l *ctxLogger) FatalFields(ctx context.Context, msg string, fields ...Field) {
+	l.zl.log(ctx, zapcore.FatalLevel, msg, fields)
+}
+
+func (l *ctxLogger) PanicFields(ctx context.Context, msg string, fields ...Field) {
+	l.zl.log(ctx, zapcore.PanicLevel, msg, fields)
+}
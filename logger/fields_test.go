@@ -0,0 +1,134 @@
+package logger
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/net/context"
+
+	"github.com/delivery-much/dm-go/middleware"
+)
+
+type order struct {
+	ID    string
+	Items int
+}
+
+func (o order) MarshalLogObject(enc ObjectEncoder) error {
+	enc.AddString("id", o.ID)
+	enc.AddInt("items", o.Items)
+	return nil
+}
+
+func TestFields(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "req-id")
+
+	t.Run("Should log the typed fields with the context fields", func(t *testing.T) {
+		l, logs := newObservedLogger(map[any]string{"store": "store_id"})
+
+		l.InfoFields(context.WithValue(ctx, "store", "store-1"), "order created",
+			String("order_id", "order-1"),
+			Int("items", 3),
+			Duration("elapsed", 2*time.Second),
+			Bool("paid", true),
+			Any("tags", []string{"promo"}),
+			Object("order", order{ID: "order-1", Items: 3}),
+		)
+
+		entries := logs.All()
+		assert.Len(t, entries, 1)
+		assert.Equal(t, zapcore.InfoLevel, entries[0].Level)
+		assert.Equal(t, map[string]any{
+			"request_id": "req-id",
+			"store_id":   "store-1",
+			"order_id":   "order-1",
+			"items":      int64(3),
+			"elapsed":    2 * time.Second,
+			"paid":       true,
+			"tags":       []any{"promo"},
+			"order":      map[string]any{"id": "order-1", "items": 3},
+		}, entries[0].ContextMap())
+	})
+
+	t.Run("Should log the fields carried by the context replacing the configured ones", func(t *testing.T) {
+		l, logs := newObservedLogger(nil)
+
+		l.WarnFields(WithFields(ctx, "request_id", "other-id", "order_id", "order-1"), "order slow")
+
+		assert.Equal(t, map[string]any{
+			"request_id": "other-id",
+			"order_id":   "order-1",
+		}, logs.All()[0].ContextMap())
+	})
+
+	t.Run("Should log the fields of the children", func(t *testing.T) {
+		l, logs := newObservedLogger(nil)
+
+		l.With("store_id", "store-1").Named("orders").ErrorFields(nil, "order failed", Int64("attempt", 2))
+
+		entries := logs.All()
+		assert.Equal(t, "orders", entries[0].LoggerName)
+		assert.Equal(t, map[string]any{"store_id": "store-1", "attempt": int64(2)}, entries[0].ContextMap())
+	})
+
+	t.Run("Should not log below the level", func(t *testing.T) {
+		l, logs := newObservedLogger(nil)
+		assert.Nil(t, l.SetLevel(INFO, 0))
+
+		l.DebugFields(ctx, "cache miss", String("key", "orders"))
+
+		assert.Zero(t, logs.Len())
+	})
+
+	t.Run("Should not sample the entries of a NeverSample context", func(t *testing.T) {
+		l, logs := newSampledLogger(&Sampling{Initial: 1, Thereafter: 100, Interval: time.Minute})
+
+		for i := 0; i < 3; i++ {
+			l.ErrorFields(NeverSample(ctx), "payment captured twice")
+			l.InfoFields(ctx, "order created")
+		}
+
+		assert.Equal(t, 3, logs.FilterMessage("payment captured twice").Len())
+		assert.Equal(t, 1, logs.FilterMessage("order created").Len())
+	})
+
+	t.Run("Should not panic without a logger", func(t *testing.T) {
+		assert.NotPanics(t, func() {
+			(&ctxLogger{zl: &zapLogger{}}).InfoFields(ctx, "order created", String("order_id", "order-1"))
+		})
+	})
+
+	t.Run("Should not allocate to log typed fields with the context fields, except for the trace ids", func(t *testing.T) {
+		if raceEnabled {
+			t.Skip("the race detector drops the pooled slices of fields")
+		}
+
+		l, err := New(Configuration{
+			IsJSON:   true,
+			Sampling: &Sampling{Disabled: true},
+			Outputs:  []Output{{Writer: io.Discard, IsJSON: true}},
+		})
+		assert.Nil(t, err)
+		// called through the implementation, since the variadic fields escape in the calls through the interface
+		cl := l.(*ctxLogger)
+
+		traceID, _ := oteltrace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+		spanID, _ := oteltrace.SpanIDFromHex("00f067aa0ba902b7")
+		ctx := WithFields(ctx, "store_id", "store-1")
+		traced := oteltrace.ContextWithSpanContext(ctx, oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+			TraceID: traceID,
+			SpanID:  spanID,
+		}))
+
+		assert.Zero(t, testing.AllocsPerRun(100, func() {
+			cl.InfoFields(ctx, "order created", String("order_id", "order-1"), Int("items", 3))
+		}))
+		assert.Equal(t, 1.0, testing.AllocsPerRun(100, func() {
+			cl.InfoFields(traced, "order created", String("order_id", "order-1"), Int("items", 3))
+		}))
+	})
+}
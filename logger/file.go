@@ -0,0 +1,231 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFileMaxSize = 100 // megabytes
+
+	// backupTimeFormat is the format of the time in the name of the rotated files
+	backupTimeFormat = "2006-01-02T15-04-05.000000000"
+)
+
+// File represents a file output, rotated when it reaches the maximum size or, with MaxFileAge, the maximum age.
+// The rotated files are kept next to it, with the time of the rotation in their names,
+// like app-2024-01-02T15-04-05.000000000.log, and removed when they exceed the retention.
+//
+// Ex.:
+//
+//	File{
+//		Path:       "/var/log/app/app.log",
+//		MaxSize:    50,
+//		MaxFileAge: 24 * time.Hour,
+//		MaxAge:     7 * 24 * time.Hour,
+//		MaxBackups: 10,
+//	}
+type File struct {
+	// Path of the file, created with its directory if it does not exist.
+	Path string
+	// MaxSize is the size in megabytes the file reaches before it is rotated. Default: 100.
+	MaxSize int
+	// MaxFileAge is the age the file reaches before it is rotated, counted from when it was opened by the logger,
+	// checked at each write. Default: rotated only by size.
+	MaxFileAge time.Duration
+	// MaxAge is the age after which the rotated files are removed. Default: kept regardless of their age.
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated files kept. Default: all of them.
+	MaxBackups int
+}
+
+// rotatingFile represents the writer of a File, safe for concurrent use.
+type rotatingFile struct {
+	config  File
+	maxSize int64
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	closed   bool
+}
+
+// newRotatingFile opens the file of the config, appending to it if it already exists.
+func newRotatingFile(config File) (*rotatingFile, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("file output has no path")
+	}
+
+	maxSize := config.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultFileMaxSize
+	}
+
+	rf := &rotatingFile{
+		config:  config,
+		maxSize: int64(maxSize) * 1024 * 1024,
+	}
+
+	err := rf.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return rf, nil
+}
+
+// open opens the file, creating it and its directory if they do not exist.
+func (rf *rotatingFile) open() error {
+	err := os.MkdirAll(filepath.Dir(rf.config.Path), 0755)
+	if err != nil {
+		return fmt.Errorf("Failed to create the log directory: %s", err)
+	}
+
+	file, err := os.OpenFile(rf.config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("Failed to open the log file: %s", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("Failed to open the log file: %s", err)
+	}
+
+	rf.file = file
+	rf.size = info.Size()
+	rf.openedAt = time.Now()
+	return nil
+}
+
+// Write writes the entry, rotating the file before it when the entry would exceed the maximum size
+// or when the file reached the maximum age.
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.closed {
+		return 0, os.ErrClosed
+	}
+
+	// an entry larger than the maximum size is written alone in a file
+	if rf.size > 0 && (rf.size+int64(len(p)) > rf.maxSize || rf.expired()) {
+		err := rf.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// expired reports whether the file reached the MaxFileAge. It must be called holding the lock.
+func (rf *rotatingFile) expired() bool {
+	return rf.config.MaxFileAge > 0 && time.Since(rf.openedAt) >= rf.config.MaxFileAge
+}
+
+// Sync commits the written entries to the disk.
+func (rf *rotatingFile) Sync() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.closed {
+		return nil
+	}
+
+	return rf.file.Sync()
+}
+
+// Close commits the written entries to the disk and closes the file. The entries written after it fail with os.ErrClosed.
+func (rf *rotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.closed {
+		return nil
+	}
+	rf.closed = true
+
+	return errors.Join(rf.file.Sync(), rf.file.Close())
+}
+
+// rotate renames the file to a backup, opens a new one and removes the backups that exceed the retention.
+// It must be called holding the lock.
+func (rf *rotatingFile) rotate() error {
+	err := rf.file.Close()
+	if err != nil {
+		return fmt.Errorf("Failed to close the log file: %s", err)
+	}
+
+	err = os.Rename(rf.config.Path, rf.backupName(time.Now()))
+	if err != nil {
+		return fmt.Errorf("Failed to rotate the log file: %s", err)
+	}
+
+	err = rf.open()
+	if err != nil {
+		return err
+	}
+
+	rf.removeBackups()
+	return nil
+}
+
+// backupName returns the name of the file rotated at the given time.
+func (rf *rotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(rf.config.Path)
+	prefix := strings.TrimSuffix(rf.config.Path, ext)
+
+	return fmt.Sprintf("%s-%s%s", prefix, t.Format(backupTimeFormat), ext)
+}
+
+// backups returns the rotated files, from the newest to the oldest.
+func (rf *rotatingFile) backups() []string {
+	ext := filepath.Ext(rf.config.Path)
+	prefix := strings.TrimSuffix(rf.config.Path, ext)
+
+	matches, _ := filepath.Glob(prefix + "-*" + ext)
+
+	var backups []string
+	for _, m := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(m, prefix+"-"), ext)
+		if _, err := time.Parse(backupTimeFormat, stamp); err == nil {
+			backups = append(backups, m)
+		}
+	}
+
+	// the time format sorts in chronological order
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups
+}
+
+// removeBackups removes the rotated files beyond MaxBackups or older than MaxAge.
+// The errors are ignored, the files are removed again in the next rotation.
+func (rf *rotatingFile) removeBackups() {
+	if rf.config.MaxBackups <= 0 && rf.config.MaxAge <= 0 {
+		return
+	}
+
+	for i, backup := range rf.backups() {
+		if rf.config.MaxBackups > 0 && i >= rf.config.MaxBackups {
+			os.Remove(backup)
+			continue
+		}
+
+		if rf.config.MaxAge > 0 {
+			info, err := os.Stat(backup)
+			if err == nil && time.Since(info.ModTime()) > rf.config.MaxAge {
+				os.Remove(backup)
+			}
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/net/context"
+
+	"github.com/delivery-much/dm-go/render"
+)
+
+// levelController represents the level of a logger, shared by its copies, that can be changed at runtime.
+type levelController struct {
+	atomic zap.AtomicLevel
+
+	mu sync.Mutex
+	// revert is the timer that reverts a temporary change, nil when there is none
+	revert *time.Timer
+	// previous is the level to revert to
+	previous zapcore.Level
+}
+
+// levelChange represents the body of the requests and responses of the LevelHandler.
+type levelChange struct {
+	Level string `json:"level"`
+	// RevertAfter is a duration, like "10m", after which the level is reverted to the previous one
+	RevertAfter string `json:"revert_after,omitempty"`
+}
+
+func newLevelController(level zapcore.Level) *levelController {
+	return &levelController{
+		atomic:   zap.NewAtomicLevelAt(level),
+		previous: level,
+	}
+}
+
+// parseLevel returns the zap level of the given level, or an error if it is unknown.
+func parseLevel(level string) (zapcore.Level, error) {
+	switch level {
+	case DEBUG, INFO, WARN, ERROR, FATAL:
+		return getZapLevel(level), nil
+	default:
+		return zapcore.InfoLevel, fmt.Errorf("unknown log level %q", level)
+	}
+}
+
+// set changes the level, reverting it to the level before the change after revertAfter, if it is positive.
+// When a previous change was still going to be reverted, the new change reverts to the level before both.
+func (lc *levelController) set(level zapcore.Level, revertAfter time.Duration) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if lc.revert != nil {
+		lc.revert.Stop()
+		lc.revert = nil
+	} else {
+		lc.previous = lc.atomic.Level()
+	}
+	lc.atomic.SetLevel(level)
+
+	if revertAfter <= 0 {
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(revertAfter, func() {
+		lc.mu.Lock()
+		defer lc.mu.Unlock()
+
+		// the timer may fire while a newer change is stopping it
+		if lc.revert != timer {
+			return
+		}
+		lc.atomic.SetLevel(lc.previous)
+		lc.revert = nil
+	})
+	lc.revert = timer
+}
+
+// toggle changes the level to the given one, or reverts it to the previous level if it is already the given one.
+func (lc *levelController) toggle(level zapcore.Level, revertAfter time.Duration) {
+	lc.mu.Lock()
+	if lc.atomic.Level() == level && lc.previous != level {
+		previous := lc.previous
+		lc.mu.Unlock()
+		lc.set(previous, 0)
+		return
+	}
+	lc.mu.Unlock()
+
+	lc.set(level, revertAfter)
+}
+
+// ServeHTTP serves the level as JSON on GET, and changes it on PUT.
+func (lc *levelController) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var change levelChange
+		err := json.NewDecoder(r.Body).Decode(&change)
+		if err != nil {
+			render.RespondError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %s", err))
+			return
+		}
+
+		level, err := parseLevel(change.Level)
+		if err != nil {
+			render.RespondError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		var revertAfter time.Duration
+		if change.RevertAfter != "" {
+			revertAfter, err = time.ParseDuration(change.RevertAfter)
+			if err != nil {
+				render.RespondError(w, http.StatusBadRequest, fmt.Errorf("invalid revert_after: %s", err))
+				return
+			}
+		}
+
+		lc.set(level, revertAfter)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		render.RespondError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	render.RespondJSON(w, http.StatusOK, levelChange{Level: lc.atomic.Level().String()})
+}
+
+// watchSignal toggles the level each time the signal is received, until the context is done.
+func (lc *levelController) watchSignal(ctx context.Context, sig os.Signal, level zapcore.Level, revertAfter time.Duration) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, sig)
+
+	go func() {
+		defer signal.Stop(signals)
+
+		for {
+			select {
+			case <-signals:
+				lc.toggle(level, revertAfter)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// SetLevel changes the level of the logger at runtime.
+// If revertAfter is positive, the level is reverted to the previous one after it.
+//
+// Ex.:
+//
+//	// logs debug messages for the next 10 minutes
+//	err := logger.SetLevel(logger.DEBUG, 10*time.Minute)
+func SetLevel(level string, revertAfter time.Duration) error {
+	return Default().SetLevel(level, revertAfter)
+}
+
+// GetLevel returns the current level of the logger.
+func GetLevel() string {
+	return Default().Level()
+}
+
+// LevelHandler returns an http.Handler that serves the level of the logger on GET
+// and changes it on PUT, with the body {"level": "debug", "revert_after": "10m"},
+// where the optional revert_after reverts the level to the previous one after the duration.
+//
+// Ex.:
+//
+//	r := chi.NewRouter()
+//	r.Handle("/log/level", logger.LevelHandler())
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if log == nil {
+			render.RespondError(w, http.StatusServiceUnavailable, fmt.Errorf("logger not initialized"))
+			return
+		}
+
+		log.zl.level.ServeHTTP(w, r)
+	})
+}
+
+// ToggleLevelOnSignal toggles the level of the logger between the given level and the previous one
+// each time the signal is received, until the context is done.
+// If revertAfter is positive, the toggled level is reverted automatically after it.
+//
+// Ex.:
+//
+//	// kill -USR1 <pid> turns debug logs on for 10 minutes, or off if they are on
+//	logger.ToggleLevelOnSignal(ctx, syscall.SIGUSR1, logger.DEBUG, 10*time.Minute)
+func ToggleLevelOnSignal(ctx context.Context, sig os.Signal, level string, revertAfter time.Duration) error {
+	zl, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+	if log == nil {
+		return fmt.Errorf("logger not initialized")
+	}
+
+	log.zl.level.watchSignal(ctx, sig, zl, revertAfter)
+	return nil
+}
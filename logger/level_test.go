@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLevelController(t *testing.T) {
+	t.Run("Should change the level and revert it after the duration", func(t *testing.T) {
+		lc := newLevelController(zapcore.InfoLevel)
+
+		lc.set(zapcore.DebugLevel, 10*time.Millisecond)
+
+		assert.Equal(t, zapcore.DebugLevel, lc.atomic.Level())
+		assert.Eventually(t, func() bool {
+			return lc.atomic.Level() == zapcore.InfoLevel
+		}, time.Second, time.Millisecond)
+	})
+	t.Run("Should revert to the level before every pending change", func(t *testing.T) {
+		lc := newLevelController(zapcore.WarnLevel)
+
+		lc.set(zapcore.InfoLevel, time.Hour)
+		lc.set(zapcore.DebugLevel, 10*time.Millisecond)
+
+		assert.Eventually(t, func() bool {
+			return lc.atomic.Level() == zapcore.WarnLevel
+		}, time.Second, time.Millisecond)
+	})
+	t.Run("Should not revert a permanent change", func(t *testing.T) {
+		lc := newLevelController(zapcore.InfoLevel)
+
+		lc.set(zapcore.DebugLevel, 10*time.Millisecond)
+		lc.set(zapcore.ErrorLevel, 0)
+		time.Sleep(20 * time.Millisecond)
+
+		assert.Equal(t, zapcore.ErrorLevel, lc.atomic.Level())
+	})
+	t.Run("Should toggle between the level and the previous one", func(t *testing.T) {
+		lc := newLevelController(zapcore.InfoLevel)
+
+		lc.toggle(zapcore.DebugLevel, 0)
+		assert.Equal(t, zapcore.DebugLevel, lc.atomic.Level())
+
+		lc.toggle(zapcore.DebugLevel, 0)
+		assert.Equal(t, zapcore.InfoLevel, lc.atomic.Level())
+	})
+}
+
+func TestLevelHandler(t *testing.T) {
+	serve := func(lc *levelController, method, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		lc.ServeHTTP(w, httptest.NewRequest(method, "/log/level", strings.NewReader(body)))
+		return w
+	}
+
+	t.Run("Should return the current level", func(t *testing.T) {
+		w := serve(newLevelController(zapcore.WarnLevel), http.MethodGet, "")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"level": "warn"}`, w.Body.String())
+	})
+	t.Run("Should change the level", func(t *testing.T) {
+		lc := newLevelController(zapcore.InfoLevel)
+
+		w := serve(lc, http.MethodPut, `{"level": "debug", "revert_after": "1h"}`)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"level": "debug"}`, w.Body.String())
+		assert.Equal(t, zapcore.DebugLevel, lc.atomic.Level())
+		assert.NotNil(t, lc.revert)
+	})
+	t.Run("Should reject unknown levels and invalid durations", func(t *testing.T) {
+		lc := newLevelController(zapcore.InfoLevel)
+
+		assert.Equal(t, http.StatusBadRequest, serve(lc, http.MethodPut, `{"level": "verbose"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, serve(lc, http.MethodPut, `{"level": "debug", "revert_after": "soon"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, serve(lc, http.MethodPut, `{`).Code)
+		assert.Equal(t, zapcore.InfoLevel, lc.atomic.Level())
+	})
+	t.Run("Should reject other methods", func(t *testing.T) {
+		w := serve(newLevelController(zapcore.InfoLevel), http.MethodPost, "")
+
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	})
+}
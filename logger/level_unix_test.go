@@ -0,0 +1,28 @@
+//go:build unix
+
+package logger
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/net/context"
+)
+
+func TestLevelControllerSignal(t *testing.T) {
+	t.Run("Should toggle the level when the signal is received", func(t *testing.T) {
+		lc := newLevelController(zapcore.InfoLevel)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		lc.watchSignal(ctx, syscall.SIGUSR1, zapcore.DebugLevel, 0)
+		syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+
+		assert.Eventually(t, func() bool {
+			return lc.atomic.Level() == zapcore.DebugLevel
+		}, time.Second, time.Millisecond)
+	})
+}
@@ -1,10 +1,17 @@
 package logger
 
-import "golang.org/x/net/context"
+import (
+	"log/slog"
+	"time"
+
+	"go.uber.org/zap"
+
+	"golang.org/x/net/context"
+)
 
 var (
-	// A global variable so that log functions can be directly accessed
-	log *zapLogger
+	// The default instance, so that log functions can be directly accessed
+	log *ctxLogger
 )
 
 const (
@@ -29,6 +36,8 @@ type BaseFields struct {
 
 // Configuration stores the config for the logger
 // For some loggers there can only be one level across writers, for such the level of Console is picked by default
+// The Level can be changed at runtime, see SetLevel, LevelHandler and ToggleLevelOnSignal.
+// When not set, the level is info in JSON mode and debug otherwise.
 //
 // The CTXFields value maps the fields that the logger should look for in the context to its correspondent field in the logger.
 // Ex.:
@@ -42,29 +51,303 @@ type BaseFields struct {
 // when loggin, will look for the value stored in the 0 key in the provided context,
 // and log it on the "request_id" field, alongside the log message and information.
 //
-// By default, if the CTX fields are specified or not, the lib will search for a request id in the context
+// By default, if the CTX fields are specified or not, the lib will search for a request id in the context,
+// and for an OpenTelemetry span, whose trace id, span id and sampled flag are logged, see TraceFields.
 type Configuration struct {
-	IsJSON     bool
-	Level      string
-	BaseFields BaseFields
-	CTXFields  map[any]string
+	IsJSON      bool
+	Level       string
+	BaseFields  BaseFields
+	CTXFields   map[any]string
+	TraceFields TraceFields
+	// Redaction masks sensitive values before the entries are encoded, see Redaction and DefaultRedaction.
+	Redaction *Redaction
+	// Sampling limits how many entries with the same level and message are logged, see Sampling.
+	Sampling *Sampling
+	// Outputs are the destinations of the entries, each with its own level and format, see Output.
+	// Default: the standard error, in the format of IsJSON.
+	Outputs []Output
+	// StacktraceLevel is the level from which the stack trace of the call is added to the entries. Default: ERROR in JSON mode, WARN otherwise.
+	StacktraceLevel string
+	// DisableStacktrace disables the stack traces of the calls. The stack traces of the errors are still logged, see Err.
+	DisableStacktrace bool
+	// SlogDefault sets the handler of the default instance as the handler of slog.Default, see Logger.Handler,
+	// so the libraries that log through slog write in the same format. It applies only to NewLogger.
+	SlogDefault bool
+}
+
+// Logger represents an instance of the logger, with the log functions that take a context.
+//
+// The package functions log through the default instance, created by NewLogger,
+// while New returns independent instances, to be injected or to create sub-loggers with With and Named.
+//
+// Ex.:
+//
+//	l, err := logger.New(logger.Configuration{IsJSON: true})
+//	if err != nil {
+//		panic(err)
+//	}
+//
+//	orders := l.Named("orders").With("store_id", storeID)
+//	orders.Infow(ctx, "order created", "order_id", orderID)
+type Logger interface {
+	Debug(ctx context.Context, msg string)
+	Debugw(ctx context.Context, msg string, keysAndValues ...any)
+	Debugf(ctx context.Context, template string, args ...any)
+	Info(ctx context.Context, msg string)
+	Infow(ctx context.Context, msg string, keysAndValues ...any)
+	Infof(ctx context.Context, template string, args ...any)
+	Warn(ctx context.Context, msg string)
+	Warnw(ctx context.Context, msg string, keysAndValues ...any)
+	Warnf(ctx context.Context, template string, args ...any)
+	Error(ctx context.Context, msg string)
+	Errorw(ctx context.Context, msg string, keysAndValues ...any)
+	Errorf(ctx context.Context, template string, args ...any)
+	Fatal(ctx context.Context, msg string)
+	Fatalw(ctx context.Context, msg string, keysAndValues ...any)
+	Fatalf(ctx context.Context, template string, args ...any)
+	Panic(ctx context.Context, msg string)
+	Panicw(ctx context.Context, msg string, keysAndValues ...any)
+	Panicf(ctx context.Context, template string, args ...any)
+	// DebugFields, InfoFields, WarnFields, ErrorFields, FatalFields and PanicFields log the message with typed fields,
+	// without boxing the values in interfaces, for the hot paths, see Field.
+	DebugFields(ctx context.Context, msg string, fields ...Field)
+	InfoFields(ctx context.Context, msg string, fields ...Field)
+	WarnFields(ctx context.Context, msg string, fields ...Field)
+	ErrorFields(ctx context.Context, msg string, fields ...Field)
+	FatalFields(ctx context.Context, msg string, fields ...Field)
+	PanicFields(ctx context.Context, msg string, fields ...Field)
+	// With returns a child logger that adds the key and values to every entry.
+	With(keysAndValues ...any) Logger
+	// Named returns a child logger with the name appended to the name of the logger, in the "logger" field.
+	Named(name string) Logger
+	// NoCTX allows access to log functions without the need to provide a context variable
+	NoCTX() NoCTXLogger
+	// Handler returns an slog.Handler that writes through the logger, with its fields, the context fields of the records
+	// and the same format, mapping the attributes to fields and the groups to nested objects.
+	Handler() slog.Handler
+	// SetLevel changes the level at runtime, see the SetLevel function.
+	// The level is shared by the logger created by New and all the children created from it by With and Named,
+	// so changing it in a child also changes it in the parent and in the other children.
+	SetLevel(level string, revertAfter time.Duration) error
+	// Level returns the current level of the logger.
+	Level() string
+	// Sync commits the entries written to the outputs, see the Sync function.
+	Sync() error
+	// Close commits the entries written to the outputs and closes their files, see the Close function.
+	Close() error
+}
+
+// NoCTXLogger represents the log functions that do not take a context.
+type NoCTXLogger interface {
+	Debug(msg string)
+	Debugw(msg string, keysAndValues ...any)
+	Debugf(template string, args ...any)
+	Info(msg string)
+	Infow(msg string, keysAndValues ...any)
+	Infof(template string, args ...any)
+	Warn(msg string)
+	Warnw(msg string, keysAndValues ...any)
+	Warnf(template string, args ...any)
+	Error(msg string)
+	Errorw(msg string, keysAndValues ...any)
+	Errorf(template string, args ...any)
+	Fatal(msg string)
+	Fatalw(msg string, keysAndValues ...any)
+	Fatalf(template string, args ...any)
+	Panic(msg string)
+	Panicw(msg string, keysAndValues ...any)
+	Panicf(template string, args ...any)
+	AddRequestID(requestID string) NoCTXLogger
+}
+
+// ctxLogger represents the Logger implementation, that adds the context fields to the zap logger on each entry.
+type ctxLogger struct {
+	zl *zapLogger
 }
 
-// NewLogger returns an instance of logger
+// New returns a new instance of logger, independent of the default one.
+func New(config Configuration) (Logger, error) {
+	zl, err := newZapLogger(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ctxLogger{zl: zl}, nil
+}
+
+// NewLogger creates the default instance of logger, used by the package functions.
 func NewLogger(config Configuration) (err error) {
-	log, err = newZapLogger(config)
-	return
+	zl, err := newZapLogger(config)
+	if err != nil {
+		return err
+	}
+
+	log = &ctxLogger{zl: zl}
+	if config.SlogDefault {
+		slog.SetDefault(slog.New(log.Handler()))
+	}
+	return nil
 }
 
-// NoCTX allows access to log functions without the need to provide a context variable
-func NoCTX() *zapLogger {
+// Default returns the default instance of logger, used by the package functions,
+// or a logger that discards every entry if NewLogger was not called.
+func Default() Logger {
 	if log == nil {
-		return &zapLogger{}
+		return &ctxLogger{zl: &zapLogger{}}
 	}
 
 	return log
 }
 
+// Sync commits the entries written to the outputs of the default instance, such as the files, see Output.
+// It should be called before the application exits.
+func Sync() error {
+	return Default().Sync()
+}
+
+// Close commits the entries written to the outputs of the default instance and closes their files, see Output.
+// The logger and its children must not be used after it, since the entries written to the files are lost.
+//
+// Ex.:
+//
+//	defer logger.Close()
+func Close() error {
+	return Default().Close()
+}
+
+// NoCTX allows access to log functions without the need to provide a context variable
+func NoCTX() NoCTXLogger {
+	return Default().NoCTX()
+}
+
+func (l *ctxLogger) With(keysAndValues ...any) Logger {
+	if l.zl.sugaredLogger == nil {
+		return l
+	}
+
+	return &ctxLogger{zl: l.zl.derive(func(sl *zap.SugaredLogger) *zap.SugaredLogger {
+		return sl.With(keysAndValues...)
+	})}
+}
+
+func (l *ctxLogger) Named(name string) Logger {
+	if l.zl.sugaredLogger == nil {
+		return l
+	}
+
+	return &ctxLogger{zl: l.zl.derive(func(sl *zap.SugaredLogger) *zap.SugaredLogger {
+		return sl.Named(name)
+	})}
+}
+
+func (l *ctxLogger) NoCTX() NoCTXLogger {
+	return l.zl
+}
+
+func (l *ctxLogger) SetLevel(level string, revertAfter time.Duration) error {
+	zl, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	if l.zl.level != nil {
+		l.zl.level.set(zl, revertAfter)
+	}
+	return nil
+}
+
+func (l *ctxLogger) Sync() error {
+	if l.zl.logger == nil {
+		return nil
+	}
+
+	return l.zl.logger.Sync()
+}
+
+func (l *ctxLogger) Close() error {
+	return closeFiles(l.zl.files)
+}
+
+func (l *ctxLogger) Level() string {
+	if l.zl.level == nil {
+		return ""
+	}
+
+	return l.zl.level.atomic.Level().String()
+}
+
+func (l *ctxLogger) Debug(ctx context.Context, msg string) {
+	l.zl.addCTXFields(ctx).Debug(msg)
+}
+
+func (l *ctxLogger) Debugw(ctx context.Context, msg string, keysAndValues ...any) {
+	l.zl.addCTXFields(ctx).Debugw(msg, keysAndValues...)
+}
+
+func (l *ctxLogger) Debugf(ctx context.Context, template string, args ...any) {
+	l.zl.addCTXFields(ctx).Debugf(template, args...)
+}
+
+func (l *ctxLogger) Info(ctx context.Context, msg string) {
+	l.zl.addCTXFields(ctx).Info(msg)
+}
+
+func (l *ctxLogger) Infow(ctx context.Context, msg string, keysAndValues ...any) {
+	l.zl.addCTXFields(ctx).Infow(msg, keysAndValues...)
+}
+
+func (l *ctxLogger) Infof(ctx context.Context, template string, args ...any) {
+	l.zl.addCTXFields(ctx).Infof(template, args...)
+}
+
+func (l *ctxLogger) Warn(ctx context.Context, msg string) {
+	l.zl.addCTXFields(ctx).Warn(msg)
+}
+
+func (l *ctxLogger) Warnw(ctx context.Context, msg string, keysAndValues ...any) {
+	l.zl.addCTXFields(ctx).Warnw(msg, keysAndValues...)
+}
+
+func (l *ctxLogger) Warnf(ctx context.Context, template string, args ...any) {
+	l.zl.addCTXFields(ctx).Warnf(template, args...)
+}
+
+func (l *ctxLogger) Error(ctx context.Context, msg string) {
+	l.zl.addCTXFields(ctx).Error(msg)
+}
+
+func (l *ctxLogger) Errorw(ctx context.Context, msg string, keysAndValues ...any) {
+	l.zl.addCTXFields(ctx).Errorw(msg, keysAndValues...)
+}
+
+func (l *ctxLogger) Errorf(ctx context.Context, template string, args ...any) {
+	l.zl.addCTXFields(ctx).Errorf(template, args...)
+}
+
+func (l *ctxLogger) Fatal(ctx context.Context, msg string) {
+	l.zl.addCTXFields(ctx).Fatal(msg)
+}
+
+func (l *ctxLogger) Fatalw(ctx context.Context, msg string, keysAndValues ...any) {
+	l.zl.addCTXFields(ctx).Fatalw(msg, keysAndValues...)
+}
+
+func (l *ctxLogger) Fatalf(ctx context.Context, template string, args ...any) {
+	l.zl.addCTXFields(ctx).Fatalf(template, args...)
+}
+
+func (l *ctxLogger) Panic(ctx context.Context, msg string) {
+	l.zl.addCTXFields(ctx).Panic(msg)
+}
+
+func (l *ctxLogger) Panicw(ctx context.Context, msg string, keysAndValues ...any) {
+	l.zl.addCTXFields(ctx).Panicw(msg, keysAndValues...)
+}
+
+func (l *ctxLogger) Panicf(ctx context.Context, template string, args ...any) {
+	l.zl.addCTXFields(ctx).Panicf(template, args...)
+}
+
 // getBaseFields returns the map of basic fields that should appear in every log output.
 func getBaseFields(baseFields BaseFields) map[string]any {
 	initFields := make(map[string]any)
@@ -83,138 +366,102 @@ func getBaseFields(baseFields BaseFields) map[string]any {
 
 // Debug log a debug message.
 func Debug(ctx context.Context, msg string) {
-	if log != nil {
-		log.addCTXFields(ctx).Debug(msg)
-	}
+	Default().Debug(ctx, msg)
 }
 
 // Debugw logs a message with some additional context,
 // With key and values, example: log.Debugw("message", "url", url, "attempt", 3)
 // Keys in key-value pairs should be strings.
 func Debugw(ctx context.Context, msg string, keysAndValues ...any) {
-	if log != nil {
-		log.addCTXFields(ctx).Debugw(msg, keysAndValues...)
-	}
+	Default().Debugw(ctx, msg, keysAndValues...)
 }
 
 // Debugf uses fmt.Sprintf to log a templated message.
 func Debugf(ctx context.Context, template string, args ...any) {
-	if log != nil {
-		log.addCTXFields(ctx).Debugf(template, args...)
-	}
+	Default().Debugf(ctx, template, args...)
 }
 
 // Info log a info message.
 func Info(ctx context.Context, msg string) {
-	if log != nil {
-		log.addCTXFields(ctx).Info(msg)
-	}
+	Default().Info(ctx, msg)
 }
 
 // Infow logs a message with some additional context,
 // With key and values, example: log.Infow("message", "url", url, "attempt", 3)
 // Keys in key-value pairs should be strings.
 func Infow(ctx context.Context, msg string, keysAndValues ...any) {
-	if log != nil {
-		log.addCTXFields(ctx).Infow(msg, keysAndValues...)
-	}
+	Default().Infow(ctx, msg, keysAndValues...)
 }
 
 // Infof uses fmt.Sprintf to log a templated message.
 func Infof(ctx context.Context, template string, args ...any) {
-	if log != nil {
-		log.addCTXFields(ctx).Infof(template, args...)
-	}
+	Default().Infof(ctx, template, args...)
 }
 
 // Warn log a warn message.
 func Warn(ctx context.Context, msg string) {
-	if log != nil {
-		log.addCTXFields(ctx).Warn(msg)
-	}
+	Default().Warn(ctx, msg)
 }
 
 // Warnw logs a message with some additional context,
 // With key and values, example: log.Warnw("message", "url", url, "attempt", 3)
 // Keys in key-value pairs should be strings.
 func Warnw(ctx context.Context, msg string, keysAndValues ...any) {
-	if log != nil {
-		log.addCTXFields(ctx).Warnw(msg, keysAndValues...)
-	}
+	Default().Warnw(ctx, msg, keysAndValues...)
 }
 
 // Warnf uses fmt.Sprintf to log a templated message.
 func Warnf(ctx context.Context, template string, args ...any) {
-	if log != nil {
-		log.addCTXFields(ctx).Warnf(template, args...)
-	}
+	Default().Warnf(ctx, template, args...)
 }
 
 // Error log a error message.
 func Error(ctx context.Context, msg string) {
-	if log != nil {
-		log.addCTXFields(ctx).Error(msg)
-	}
+	Default().Error(ctx, msg)
 }
 
 // Errorw logs a message with some additional context,
 // With key and values, example: log.Errorw("message", "url", url, "attempt", 3)
 // Keys in key-value pairs should be strings.
 func Errorw(ctx context.Context, msg string, keysAndValues ...any) {
-	if log != nil {
-		log.addCTXFields(ctx).Errorw(msg, keysAndValues...)
-	}
+	Default().Errorw(ctx, msg, keysAndValues...)
 }
 
 // Errorf uses fmt.Sprintf to log a templated message.
 func Errorf(ctx context.Context, template string, args ...any) {
-	if log != nil {
-		log.addCTXFields(ctx).Errorf(template, args...)
-	}
+	Default().Errorf(ctx, template, args...)
 }
 
 // Fatal log a fatal message.
 func Fatal(ctx context.Context, msg string) {
-	if log != nil {
-		log.addCTXFields(ctx).Fatal(msg)
-	}
+	Default().Fatal(ctx, msg)
 }
 
 // Fatalw logs a message with some additional context,
 // With key and values, example: log.Fatalw("message", "url", url, "attempt", 3)
 // Keys in key-value pairs should be strings.
 func Fatalw(ctx context.Context, msg string, keysAndValues ...any) {
-	if log != nil {
-		log.addCTXFields(ctx).Fatalw(msg, keysAndValues...)
-	}
+	Default().Fatalw(ctx, msg, keysAndValues...)
 }
 
 // Fatalf uses fmt.Sprintf to log a templated message.
 func Fatalf(ctx context.Context, template string, args ...any) {
-	if log != nil {
-		log.addCTXFields(ctx).Fatalf(template, args...)
-	}
+	Default().Fatalf(ctx, template, args...)
 }
 
 // Panic log a panic message.
 func Panic(ctx context.Context, msg string) {
-	if log != nil {
-		log.addCTXFields(ctx).Panic(msg)
-	}
+	Default().Panic(ctx, msg)
 }
 
 // Panicw logs a message with some additional context,
 // With key and values, example: log.Panicw("message", "url", url, "attempt", 3)
 // Keys in key-value pairs should be strings.
 func Panicw(ctx context.Context, msg string, keysAndValues ...any) {
-	if log != nil {
-		log.addCTXFields(ctx).Panicw(msg, keysAndValues...)
-	}
+	Default().Panicw(ctx, msg, keysAndValues...)
 }
 
 // Panicf uses fmt.Sprintf to log a templated message.
 func Panicf(ctx context.Context, template string, args ...any) {
-	if log != nil {
-		log.addCTXFields(ctx).Panicf(template, args...)
-	}
+	Default().Panicf(ctx, template, args...)
 }
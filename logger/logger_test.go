@@ -0,0 +1,149 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/delivery-much/dm-go/middleware"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"golang.org/x/net/context"
+)
+
+// newObservedLogger returns a logger that records its entries, with the given context fields.
+func newObservedLogger(ctxFields map[any]string) (*ctxLogger, *observer.ObservedLogs) {
+	level := newLevelController(zapcore.DebugLevel)
+	core, logs := observer.New(level.atomic)
+
+	if ctxFields == nil {
+		ctxFields = map[any]string{}
+	}
+	ctxFields[middleware.RequestIDKey] = requestIDField
+
+	logger := zap.New(core)
+	return &ctxLogger{zl: &zapLogger{
+		sugaredLogger: logger.Sugar(),
+		logger:        logger,
+		ctxFields:     newCTXKeys(ctxFields),
+		level:         level,
+	}}, logs
+}
+
+func TestLogger(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "req-id")
+
+	t.Run("Should log the key and values with the context fields", func(t *testing.T) {
+		l, logs := newObservedLogger(map[any]string{"store": "store_id"})
+
+		l.Infow(context.WithValue(ctx, "store", "store-1"), "order created", "order_id", "order-1")
+
+		entries := logs.All()
+		assert.Len(t, entries, 1)
+		assert.Equal(t, "order created", entries[0].Message)
+		assert.Equal(t, map[string]any{
+			"order_id":   "order-1",
+			"store_id":   "store-1",
+			"request_id": "req-id",
+		}, entries[0].ContextMap())
+	})
+	t.Run("Should add the fields of With and the name of Named only to the children", func(t *testing.T) {
+		l, logs := newObservedLogger(nil)
+
+		child := l.Named("orders").With("store_id", "store-1")
+		child.Info(ctx, "child")
+		l.Info(ctx, "parent")
+
+		entries := logs.All()
+		assert.Equal(t, "orders", entries[0].LoggerName)
+		assert.Equal(t, "store-1", entries[0].ContextMap()["store_id"])
+		assert.Equal(t, "", entries[1].LoggerName)
+		assert.NotContains(t, entries[1].ContextMap(), "store_id")
+	})
+	t.Run("Should share the level with the children", func(t *testing.T) {
+		l, logs := newObservedLogger(nil)
+		child := l.With("store_id", "store-1")
+
+		assert.Nil(t, l.SetLevel(WARN, 0))
+		child.Info(ctx, "filtered")
+		child.Warn(ctx, "logged")
+
+		assert.Equal(t, WARN, child.Level())
+		assert.Equal(t, 1, logs.Len())
+		assert.NotNil(t, l.SetLevel("verbose", time.Minute))
+	})
+	t.Run("Should change the level of the parent and of the siblings when a child changes it", func(t *testing.T) {
+		l, _ := newObservedLogger(nil)
+		child := l.Named("orders")
+		sibling := l.With("store_id", "store-1")
+
+		assert.Nil(t, child.SetLevel(ERROR, 0))
+
+		assert.Equal(t, ERROR, l.Level())
+		assert.Equal(t, ERROR, sibling.Level())
+	})
+	t.Run("Should log without context", func(t *testing.T) {
+		l, logs := newObservedLogger(nil)
+
+		l.NoCTX().AddRequestID("req-id").Errorf("failed %d times", 3)
+
+		entries := logs.All()
+		assert.Equal(t, "failed 3 times", entries[0].Message)
+		assert.Equal(t, "req-id", entries[0].ContextMap()[requestIDField])
+	})
+	t.Run("Should discard the entries of the default logger when it is not created", func(t *testing.T) {
+		log = nil
+
+		assert.NotPanics(t, func() {
+			Infow(ctx, "discarded", "key", "value")
+			NoCTX().AddRequestID("req-id").Info("discarded")
+			Default().With("key", "value").Named("name").Error(ctx, "discarded")
+		})
+	})
+}
+
+func TestContextFields(t *testing.T) {
+	t.Run("Should log the fields carried by the context and its children", func(t *testing.T) {
+		l, logs := newObservedLogger(nil)
+		ctx := WithFields(context.Background(), "order_id", "order-1", "store_id", "store-1")
+		child, cancel := context.WithCancel(WithFields(ctx, "payment_id", "payment-1"))
+		defer cancel()
+
+		l.Info(child, "order paid")
+		l.Info(ctx, "order created")
+
+		entries := logs.All()
+		assert.Equal(t, map[string]any{
+			"order_id":   "order-1",
+			"store_id":   "store-1",
+			"payment_id": "payment-1",
+		}, entries[0].ContextMap())
+		assert.Equal(t, map[string]any{
+			"order_id": "order-1",
+			"store_id": "store-1",
+		}, entries[1].ContextMap())
+	})
+	t.Run("Should replace the value of a key added again, and of the configured context fields", func(t *testing.T) {
+		l, logs := newObservedLogger(nil)
+		ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "req-id")
+		ctx = WithFields(ctx, "status", "pending", requestIDField, "overridden")
+		ctx = WithFields(ctx, "status", "paid")
+
+		l.Info(ctx, "order updated")
+
+		assert.Equal(t, map[string]any{
+			"status":       "paid",
+			requestIDField: "overridden",
+		}, logs.All()[0].ContextMap())
+		assert.Equal(t, []any{"status", "paid", requestIDField, "overridden"}, Fields(ctx))
+	})
+	t.Run("Should not change the fields of the parent context", func(t *testing.T) {
+		ctx := WithFields(context.Background(), "status", "pending")
+
+		WithFields(ctx, "status", "paid", "order_id", "order-1")
+
+		assert.Equal(t, []any{"status", "pending"}, Fields(ctx))
+		assert.Empty(t, Fields(context.Background()))
+	})
+}
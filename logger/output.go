@@ -0,0 +1,155 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Output represents a destination of the entries, with its own level and format.
+// The entries are written to the File when it is set, to the Writer otherwise, and to the standard error when neither is set.
+//
+// Ex.:
+//
+//	// debug entries in a rotated file, info and above as JSON in the standard output
+//	Configuration{
+//		Level: logger.DEBUG,
+//		Outputs: []logger.Output{
+//			{File: &logger.File{Path: "/var/log/app/debug.log", MaxBackups: 5}},
+//			{Writer: os.Stdout, Level: logger.INFO, IsJSON: true},
+//		},
+//	}
+type Output struct {
+	// Writer receives the entries.
+	Writer io.Writer
+	// File writes the entries to a rotated file, see File.
+	File *File
+	// Level is the minimum level of the entries of the output.
+	// The level of the logger still applies, so entries below it are not written to any output. Default: the level of the logger.
+	Level string
+	// IsJSON encodes the entries as JSON, with the BaseFields of the Configuration. Otherwise they are encoded for the console.
+	IsJSON bool
+}
+
+// jsonEncoderConfig returns the encoder config of the JSON entries.
+func jsonEncoderConfig() zapcore.EncoderConfig {
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.MessageKey = "message"
+	encoderConfig.TimeKey = "time"
+	encoderConfig.StacktraceKey = "stack"
+	encoderConfig.EncodeTime = zapcore.RFC3339TimeEncoder
+
+	return encoderConfig
+}
+
+// consoleEncoderConfig returns the encoder config of the console entries.
+func consoleEncoderConfig() zapcore.EncoderConfig {
+	encoderConfig := zap.NewDevelopmentEncoderConfig()
+	encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+
+	return encoderConfig
+}
+
+// core returns the core that writes the entries of the output enabled by the level of the logger,
+// and the rotating file of the output, nil if it does not write to a File.
+func (o Output) core(level *levelController, baseFields BaseFields) (zapcore.Core, *rotatingFile, error) {
+	var ws zapcore.WriteSyncer
+	var rf *rotatingFile
+	switch {
+	case o.File != nil:
+		var err error
+		rf, err = newRotatingFile(*o.File)
+		if err != nil {
+			return nil, nil, err
+		}
+		ws = rf
+	case o.Writer != nil:
+		ws = zapcore.Lock(zapcore.AddSync(o.Writer))
+	default:
+		ws = zapcore.Lock(os.Stderr)
+	}
+
+	enabler := zapcore.LevelEnabler(level.atomic)
+	if o.Level != "" {
+		min, err := parseLevel(o.Level)
+		if err != nil {
+			if rf != nil {
+				rf.Close()
+			}
+			return nil, nil, err
+		}
+		enabler = zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+			return l >= min && level.atomic.Enabled(l)
+		})
+	}
+
+	if !o.IsJSON {
+		encoderConfig := consoleEncoderConfig()
+		if o.File != nil {
+			// no color codes in the files
+			encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		}
+		return zapcore.NewCore(zapcore.NewConsoleEncoder(encoderConfig), ws, enabler), rf, nil
+	}
+
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(jsonEncoderConfig()), ws, enabler)
+	return core.With(baseFieldsOf(baseFields)), rf, nil
+}
+
+// baseFieldsOf returns the zap fields of the base fields, sorted by key.
+func baseFieldsOf(baseFields BaseFields) []zapcore.Field {
+	initFields := getBaseFields(baseFields)
+
+	keys := make([]string, 0, len(initFields))
+	for k := range initFields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fields := make([]zapcore.Field, 0, len(keys))
+	for _, k := range keys {
+		fields = append(fields, zap.Any(k, initFields[k]))
+	}
+
+	return fields
+}
+
+// newOutputsLogger returns a zap logger that writes to the Outputs of the Configuration, with the core of each output wrapped by wrap,
+// and the rotating files of the outputs.
+func newOutputsLogger(config Configuration, level *levelController, wrap func(zapcore.Core) zapcore.Core) (*zap.Logger, []*rotatingFile, error) {
+	cores := make([]zapcore.Core, 0, len(config.Outputs))
+	var files []*rotatingFile
+	for i, o := range config.Outputs {
+		core, rf, err := o.core(level, config.BaseFields)
+		if err != nil {
+			closeFiles(files)
+			return nil, nil, fmt.Errorf("Failed to create the output %d: %s", i, err)
+		}
+		cores = append(cores, wrap(core))
+		if rf != nil {
+			files = append(files, rf)
+		}
+	}
+
+	opts := []zap.Option{zap.ErrorOutput(zapcore.Lock(os.Stderr))}
+	if !config.IsJSON {
+		opts = append(opts, zap.Development(), zap.AddCaller())
+	}
+
+	return zap.New(zapcore.NewTee(cores...), opts...), files, nil
+}
+
+// closeFiles closes the rotating files, returning the errors of all of them.
+func closeFiles(files []*rotatingFile) error {
+	var errs []error
+	for _, rf := range files {
+		errs = append(errs, rf.Close())
+	}
+
+	return errors.Join(errs...)
+}
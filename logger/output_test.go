@@ -0,0 +1,240 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestOutputs(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should write each output with its own level and format", func(t *testing.T) {
+		var debug, info bytes.Buffer
+		l, err := New(Configuration{
+			Level:      DEBUG,
+			BaseFields: BaseFields{ServiceName: "orders"},
+			Outputs: []Output{
+				{Writer: &debug},
+				{Writer: &info, Level: INFO, IsJSON: true},
+			},
+		})
+		assert.Nil(t, err)
+
+		l.Debug(ctx, "cache miss")
+		l.Infow(ctx, "order created", "order_id", "order-1")
+
+		assert.Contains(t, debug.String(), "cache miss")
+		assert.Contains(t, debug.String(), "order created")
+		assert.NotContains(t, debug.String(), "service_name")
+
+		lines := strings.Split(strings.TrimSpace(info.String()), "\n")
+		assert.Len(t, lines, 1)
+
+		var entry map[string]any
+		assert.Nil(t, json.Unmarshal([]byte(lines[0]), &entry))
+		assert.Equal(t, "order created", entry["message"])
+		assert.Equal(t, "order-1", entry["order_id"])
+		assert.Equal(t, "orders", entry["service_name"])
+	})
+
+	t.Run("Should apply the level of the logger to every output", func(t *testing.T) {
+		var out bytes.Buffer
+		l, err := New(Configuration{
+			Level:   DEBUG,
+			Outputs: []Output{{Writer: &out, Level: DEBUG}},
+		})
+		assert.Nil(t, err)
+
+		assert.Nil(t, l.SetLevel(WARN, 0))
+		l.Info(ctx, "order created")
+
+		assert.Empty(t, out.String())
+	})
+
+	t.Run("Should redact the entries of each output with its own level", func(t *testing.T) {
+		var debug, info bytes.Buffer
+		l, err := New(Configuration{
+			Level:     DEBUG,
+			Redaction: &Redaction{Fields: map[string]func(string) string{"password": Redact}},
+			Outputs: []Output{
+				{Writer: &debug, IsJSON: true},
+				{Writer: &info, Level: INFO, IsJSON: true},
+			},
+		})
+		assert.Nil(t, err)
+
+		l.Debugw(ctx, "login", "password", "secret")
+
+		assert.Contains(t, debug.String(), `"password":"[REDACTED]"`)
+		assert.Empty(t, info.String())
+	})
+
+	t.Run("Should write to a file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "logs", "app.log")
+		l, err := New(Configuration{
+			Outputs: []Output{
+				{File: &File{Path: path}},
+				{File: &File{Path: path + ".json"}, IsJSON: true},
+			},
+		})
+		assert.Nil(t, err)
+
+		defer l.Close()
+
+		l.Info(ctx, "order created")
+
+		b, err := os.ReadFile(path)
+		assert.Nil(t, err)
+		assert.Contains(t, string(b), "\tINFO\t")
+		assert.Contains(t, string(b), "order created")
+
+		b, err = os.ReadFile(path + ".json")
+		assert.Nil(t, err)
+		assert.Contains(t, string(b), `"message":"order created"`)
+	})
+
+	t.Run("Should sync and close the files of the outputs", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app.log")
+		l, err := New(Configuration{
+			Outputs: []Output{
+				{File: &File{Path: path}, IsJSON: true},
+				{Writer: &bytes.Buffer{}},
+			},
+		})
+		assert.Nil(t, err)
+
+		l.With("store_id", "store-1").Info(ctx, "order created")
+		assert.Nil(t, l.Sync())
+		assert.Nil(t, l.Named("orders").Close())
+
+		files := l.(*ctxLogger).zl.files
+		assert.Len(t, files, 1)
+		assert.True(t, files[0].closed)
+		assert.Nil(t, l.Close())
+
+		b, err := os.ReadFile(path)
+		assert.Nil(t, err)
+		assert.Contains(t, string(b), `"message":"order created"`)
+	})
+
+	t.Run("Should not fail to sync and close without a logger", func(t *testing.T) {
+		l := &ctxLogger{zl: &zapLogger{}}
+
+		assert.Nil(t, l.Sync())
+		assert.Nil(t, l.Close())
+	})
+
+	t.Run("Should fail with an invalid output", func(t *testing.T) {
+		_, err := New(Configuration{Outputs: []Output{{Writer: &bytes.Buffer{}, Level: "verbose"}}})
+		assert.ErrorContains(t, err, "Failed to create the output 0")
+
+		_, err = New(Configuration{Outputs: []Output{{File: &File{}}}})
+		assert.ErrorContains(t, err, "file output has no path")
+	})
+}
+
+func TestRotatingFile(t *testing.T) {
+	// newFile returns a rotating file with a maximum size of a few bytes, for the tests
+	newFile := func(t *testing.T, config File) *rotatingFile {
+		rf, err := newRotatingFile(config)
+		assert.Nil(t, err)
+		rf.maxSize = 10
+		t.Cleanup(func() { rf.Close() })
+		return rf
+	}
+
+	t.Run("Should rotate the file when it reaches the maximum size", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app.log")
+		rf := newFile(t, File{Path: path})
+
+		rf.Write([]byte("entry 1\n"))
+		rf.Write([]byte("entry 2\n"))
+
+		b, _ := os.ReadFile(path)
+		assert.Equal(t, "entry 2\n", string(b))
+
+		backups := rf.backups()
+		assert.Len(t, backups, 1)
+		assert.True(t, strings.HasSuffix(backups[0], ".log"))
+
+		b, _ = os.ReadFile(backups[0])
+		assert.Equal(t, "entry 1\n", string(b))
+	})
+
+	t.Run("Should rotate the file when it reaches MaxFileAge", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app.log")
+		rf := newFile(t, File{Path: path, MaxFileAge: time.Hour})
+
+		rf.Write([]byte("1\n"))
+		rf.Write([]byte("2\n"))
+		assert.Empty(t, rf.backups())
+
+		rf.openedAt = time.Now().Add(-2 * time.Hour)
+		rf.Write([]byte("3\n"))
+
+		b, _ := os.ReadFile(path)
+		assert.Equal(t, "3\n", string(b))
+		assert.Len(t, rf.backups(), 1)
+	})
+
+	t.Run("Should not write after it is closed", func(t *testing.T) {
+		rf := newFile(t, File{Path: filepath.Join(t.TempDir(), "app.log")})
+
+		assert.Nil(t, rf.Close())
+		assert.Nil(t, rf.Close())
+		assert.Nil(t, rf.Sync())
+
+		_, err := rf.Write([]byte("entry\n"))
+		assert.ErrorIs(t, err, os.ErrClosed)
+	})
+
+	t.Run("Should append to an existing file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app.log")
+		assert.Nil(t, os.WriteFile(path, []byte("entry 1\n"), 0644))
+		rf := newFile(t, File{Path: path})
+
+		rf.Write([]byte("entry 2\n"))
+
+		assert.Len(t, rf.backups(), 1)
+	})
+
+	t.Run("Should keep only MaxBackups rotated files", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app.log")
+		rf := newFile(t, File{Path: path, MaxBackups: 2})
+
+		for i := 0; i < 5; i++ {
+			rf.Write([]byte("entry\n"))
+			rf.Write([]byte("entry\n"))
+		}
+
+		assert.Len(t, rf.backups(), 2)
+	})
+
+	t.Run("Should remove the rotated files older than MaxAge", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "app.log")
+		rf := newFile(t, File{Path: path, MaxAge: time.Hour})
+
+		old := rf.backupName(time.Now().Add(-2 * time.Hour))
+		assert.Nil(t, os.WriteFile(old, []byte("entry\n"), 0644))
+		assert.Nil(t, os.Chtimes(old, time.Now().Add(-2*time.Hour), time.Now().Add(-2*time.Hour)))
+		unrelated := filepath.Join(dir, "app-old.log")
+		assert.Nil(t, os.WriteFile(unrelated, []byte("entry\n"), 0644))
+
+		rf.Write([]byte("entry 1\n"))
+		rf.Write([]byte("entry 2\n"))
+
+		backups := rf.backups()
+		assert.Len(t, backups, 1)
+		assert.NotEqual(t, old, backups[0])
+		assert.FileExists(t, unrelated)
+	})
+}
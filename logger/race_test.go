@@ -0,0 +1,6 @@
+//go:build race
+
+package logger
+
+// raceEnabled reports whether the tests run with the race detector, which changes the allocations.
+const raceEnabled = true
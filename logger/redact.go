@@ -0,0 +1,234 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	stringutils "github.com/delivery-much/dm-go/string_utils"
+)
+
+var (
+	// EmailPattern matches email addresses.
+	EmailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	// CPFPattern matches CPFs, formatted or not.
+	CPFPattern = regexp.MustCompile(`\b\d{3}\.?\d{3}\.?\d{3}-?\d{2}\b`)
+	// CardNumberPattern matches card numbers, with the digits optionally separated by spaces or dashes.
+	CardNumberPattern = regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`)
+	// PhonePattern matches brazilian phone numbers, with or without the country code.
+	PhonePattern = regexp.MustCompile(`(?:\+?55\s?)?\(?\b\d{2}\)?\s?9?\d{4}-?\d{4}\b`)
+)
+
+// Redaction represents the masking of sensitive values before the entries are encoded.
+//
+// The values of the Fields are masked wherever they appear: in the key and values of the entry,
+// in the fields of the context, and in the fields of nested structs and maps, matched by their JSON names.
+// The matches of the Patterns are masked in every string value and in the message.
+//
+// Ex.:
+//
+//	Redaction{
+//		Fields: map[string]func(string) string{
+//			"password": logger.Redact,
+//			"email":    stringutils.MaskEmail,
+//			"document": nil, // the default mask
+//		},
+//		Patterns: []*regexp.Regexp{logger.EmailPattern, logger.CPFPattern},
+//	}
+type Redaction struct {
+	// Fields maps the names of the fields to be masked, case insensitive, to the function that masks their values.
+	// A nil function uses the default mask: stringutils.MaskEmail for emails and stringutils.MaskString otherwise.
+	Fields map[string]func(string) string
+	// Patterns are masked with the default mask.
+	Patterns []*regexp.Regexp
+}
+
+// DefaultRedaction returns a Redaction that redacts passwords, secrets and tokens, masks
+// emails, documents, phone numbers and card numbers by field name, and emails, card numbers and CPFs by pattern.
+// The PhonePattern is not included, since it also matches other numbers of the same length.
+func DefaultRedaction() *Redaction {
+	return &Redaction{
+		Fields: map[string]func(string) string{
+			"password":      Redact,
+			"secret":        Redact,
+			"token":         Redact,
+			"authorization": Redact,
+			"email":         stringutils.MaskEmail,
+			"document":      stringutils.MaskString,
+			"cpf":           stringutils.MaskString,
+			"cnpj":          stringutils.MaskString,
+			"phone":         stringutils.MaskString,
+			"card_number":   stringutils.MaskString,
+		},
+		Patterns: []*regexp.Regexp{EmailPattern, CardNumberPattern, CPFPattern},
+	}
+}
+
+// Redact replaces the whole value, for values that must not be partially shown, like passwords.
+func Redact(string) string {
+	return "[REDACTED]"
+}
+
+// mask is the default mask of the values.
+func mask(value string) string {
+	if strings.Contains(value, "@") {
+		return stringutils.MaskEmail(value)
+	}
+
+	return stringutils.MaskString(value)
+}
+
+// redactor applies a Redaction.
+type redactor struct {
+	// fields maps the lower case names of the fields to their masks
+	fields   map[string]func(string) string
+	patterns []*regexp.Regexp
+}
+
+// newRedactor returns the redactor of the Redaction, or nil if there is nothing to redact.
+func newRedactor(r *Redaction) *redactor {
+	if r == nil || (len(r.Fields) == 0 && len(r.Patterns) == 0) {
+		return nil
+	}
+
+	rd := &redactor{
+		fields:   map[string]func(string) string{},
+		patterns: r.Patterns,
+	}
+	for name, m := range r.Fields {
+		if m == nil {
+			m = mask
+		}
+		rd.fields[strings.ToLower(name)] = m
+	}
+
+	return rd
+}
+
+// fieldMask returns the mask of the field with the given name, or nil if the field is not redacted.
+func (rd *redactor) fieldMask(name string) func(string) string {
+	return rd.fields[strings.ToLower(name)]
+}
+
+// text masks the matches of the patterns in the text.
+func (rd *redactor) text(text string) string {
+	for _, p := range rd.patterns {
+		text = p.ReplaceAllStringFunc(text, mask)
+	}
+	return text
+}
+
+// value redacts a value decoded from JSON, recursively.
+func (rd *redactor) value(v any) any {
+	switch v := v.(type) {
+	case string:
+		return rd.text(v)
+	case map[string]any:
+		for k, nested := range v {
+			if m := rd.fieldMask(k); m != nil {
+				v[k] = m(stringify(nested))
+				continue
+			}
+			v[k] = rd.value(nested)
+		}
+		return v
+	case []any:
+		for i := range v {
+			v[i] = rd.value(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// field returns the redacted field.
+func (rd *redactor) field(f zapcore.Field) zapcore.Field {
+	if m := rd.fieldMask(f.Key); m != nil {
+		return zap.String(f.Key, m(stringify(fieldValue(f))))
+	}
+
+	switch f.Type {
+	case zapcore.StringType:
+		return zap.String(f.Key, rd.text(f.String))
+	case zapcore.ErrorType:
+		// the error is logged by its message, without the verbose form that could also carry sensitive values
+		err, ok := f.Interface.(error)
+		if !ok || err == nil {
+			return f
+		}
+		return zap.String(f.Key, rd.text(err.Error()))
+	case zapcore.ReflectType, zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType, zapcore.StringerType:
+		// nested values are redacted in their JSON form, the same that would be encoded
+		b, err := json.Marshal(fieldValue(f))
+		if err != nil {
+			return f
+		}
+
+		var decoded any
+		if json.Unmarshal(b, &decoded) != nil {
+			return f
+		}
+		return zap.Any(f.Key, rd.value(decoded))
+	default:
+		return f
+	}
+}
+
+// redactFields returns the redacted fields.
+func (rd *redactor) redactFields(fields []zapcore.Field) []zapcore.Field {
+	redacted := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		redacted[i] = rd.field(f)
+	}
+	return redacted
+}
+
+// fieldValue returns the value of a field, as it would be encoded.
+func fieldValue(f zapcore.Field) any {
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	return enc.Fields[f.Key]
+}
+
+// stringify returns the string of a value to be masked.
+func stringify(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case map[string]any, []any:
+		b, _ := json.Marshal(v)
+		return string(b)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// redactCore represents a zapcore.Core that redacts the fields and the message of the entries before writing them.
+type redactCore struct {
+	zapcore.Core
+	rd *redactor
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{
+		Core: c.Core.With(c.rd.redactFields(fields)),
+		rd:   c.rd,
+	}
+}
+
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = c.rd.text(ent.Message)
+	return c.Core.Write(ent, c.rd.redactFields(fields))
+}
@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"golang.org/x/net/context"
+)
+
+type customer struct {
+	Name     string   `json:"name"`
+	Email    string   `json:"email"`
+	Document string   `json:"document"`
+	Address  address  `json:"address"`
+	Notes    []string `json:"notes"`
+}
+
+type address struct {
+	Street string `json:"street"`
+	Phone  string `json:"phone"`
+}
+
+// newRedactedLogger returns a logger that records its entries after redacting them.
+func newRedactedLogger(r *Redaction) (*ctxLogger, *observer.ObservedLogs) {
+	l, logs := newObservedLogger(nil)
+	l.zl.sugaredLogger = l.zl.sugaredLogger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &redactCore{Core: core, rd: newRedactor(r)}
+	}))
+
+	return l, logs
+}
+
+func TestRedaction(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should mask the values of the fields by name, case insensitive", func(t *testing.T) {
+		l, logs := newRedactedLogger(DefaultRedaction())
+
+		l.Infow(ctx, "login", "Password", "secret-password", "email", "customer@domain.com", "document", "12345678909", "attempt", 3)
+
+		assert.Equal(t, map[string]any{
+			"Password": "[REDACTED]",
+			"email":    "cust****@domain.com",
+			"document": "12345******",
+			"attempt":  int64(3),
+		}, logs.All()[0].ContextMap())
+	})
+	t.Run("Should mask the fields of nested structs by their JSON names", func(t *testing.T) {
+		l, logs := newRedactedLogger(DefaultRedaction())
+
+		l.Infow(ctx, "customer created", "customer", customer{
+			Name:     "John",
+			Email:    "john.doe@domain.com",
+			Document: "12345678909",
+			Address:  address{Street: "Main street", Phone: "11999998888"},
+			Notes:    []string{"paid with 4111 1111 1111 1111"},
+		})
+
+		assert.Equal(t, map[string]any{
+			"name":     "John",
+			"email":    "john.***@domain.com",
+			"document": "12345******",
+			"address":  map[string]any{"street": "Main street", "phone": "11999******"},
+			"notes":    []any{"paid with 4111 1111 **** ****"},
+		}, logs.All()[0].ContextMap()["customer"])
+	})
+	t.Run("Should mask the matches of the patterns in the message, the values and the context fields", func(t *testing.T) {
+		l, logs := newRedactedLogger(&Redaction{Patterns: []*regexp.Regexp{EmailPattern, CPFPattern}})
+		ctx := WithFields(ctx, "customer", "customer@domain.com")
+
+		l.Infof(ctx, "sent to customer@domain.com, cpf %s", "123.456.789-09")
+		l.Infow(ctx, "sent", "to", "contact: customer@domain.com")
+
+		entries := logs.All()
+		assert.Equal(t, "sent to cust****@domain.com, cpf 123.456.***-**", entries[0].Message)
+		assert.Equal(t, "cust****@domain.com", entries[0].ContextMap()["customer"])
+		assert.Equal(t, "contact: cust****@domain.com", entries[1].ContextMap()["to"])
+	})
+	t.Run("Should mask the errors logged as values", func(t *testing.T) {
+		l, out := newBufferedLogger(t, Configuration{Redaction: &Redaction{
+			Fields:   map[string]func(string) string{"cause": Redact},
+			Patterns: []*regexp.Regexp{EmailPattern},
+		}})
+
+		l.Infow(ctx, "login failed",
+			"error", errors.New("user john@doe.com failed"),
+			"cause", errors.New("invalid password"),
+		)
+
+		entry := decodeEntry(t, out)
+		assert.Equal(t, "user j***@doe.com failed", entry["error"])
+		assert.Equal(t, "[REDACTED]", entry["cause"])
+	})
+	t.Run("Should not redact without configuration", func(t *testing.T) {
+		assert.Nil(t, newRedactor(nil))
+		assert.Nil(t, newRedactor(&Redaction{}))
+	})
+}
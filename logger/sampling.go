@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/net/context"
+)
+
+const (
+	defaultSamplingInitial    = 100
+	defaultSamplingThereafter = 100
+	defaultSamplingInterval   = time.Second
+)
+
+// neverSampleKey is the context key that marks the entries that must not be sampled, see NeverSample.
+type neverSampleKey struct{}
+
+// Sampling represents the sampling of the entries, that limits how many entries with the same level and message are logged,
+// so a hot path does not flood the output.
+//
+// In each Interval, the first Initial entries with the same level and message are logged,
+// and after them only every Thereafter-th entry. The other entries are dropped.
+//
+// When the Configuration has no Sampling, the entries are sampled with the defaults in JSON mode, and not sampled otherwise.
+//
+// Ex.:
+//
+//	Sampling{
+//		Initial:    10,
+//		Thereafter: 1000,
+//		OnDropped: func(level, message string) {
+//			droppedCounter.Add(ctx, 1)
+//		},
+//	}
+type Sampling struct {
+	// Disabled logs every entry.
+	Disabled bool
+	// Initial is the number of entries logged in each interval before sampling. Default: 100.
+	Initial int
+	// Thereafter is the sampling rate after the Initial entries, only every Thereafter-th entry is logged. Default: 100.
+	Thereafter int
+	// Interval is the interval in which the entries are counted. Default: 1 second.
+	Interval time.Duration
+	// OnDropped is called with the level and the message of each dropped entry, to report how many entries are dropped.
+	OnDropped func(level, message string)
+}
+
+// defaultSampling returns the sampling used when the Configuration has none.
+func defaultSampling(isJSON bool) *Sampling {
+	return &Sampling{Disabled: !isJSON}
+}
+
+// wrap returns the option that wraps the core with the sampler, or nil if the sampling is disabled.
+func (s *Sampling) wrap() zap.Option {
+	if s.Disabled {
+		return nil
+	}
+
+	initial, thereafter, interval := s.Initial, s.Thereafter, s.Interval
+	if initial <= 0 {
+		initial = defaultSamplingInitial
+	}
+	if thereafter <= 0 {
+		thereafter = defaultSamplingThereafter
+	}
+	if interval <= 0 {
+		interval = defaultSamplingInterval
+	}
+
+	var opts []zapcore.SamplerOption
+	if s.OnDropped != nil {
+		opts = append(opts, zapcore.SamplerHook(func(ent zapcore.Entry, dec zapcore.SamplingDecision) {
+			if dec&zapcore.LogDropped != 0 {
+				s.OnDropped(ent.Level.String(), ent.Message)
+			}
+		}))
+	}
+
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewSamplerWithOptions(core, interval, initial, thereafter, opts...)
+	})
+}
+
+// NeverSample returns a copy of the context whose entries are never sampled,
+// for the messages that must always be logged, like the ones of audits or alerts.
+//
+// Ex.:
+//
+//	logger.Errorw(logger.NeverSample(ctx), "payment captured twice", "payment_id", paymentID)
+func NeverSample(ctx context.Context) context.Context {
+	return context.WithValue(ctx, neverSampleKey{}, true)
+}
+
+// neverSample returns if the entries of the context must not be sampled.
+func neverSample(ctx context.Context) bool {
+	never, _ := ctx.Value(neverSampleKey{}).(bool)
+	return never
+}
@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"golang.org/x/net/context"
+)
+
+// newSampledLogger returns a logger sampled with the given sampling, and the entries logged by it.
+func newSampledLogger(s *Sampling) (*ctxLogger, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+
+	logger := zap.New(core)
+	zl := &zapLogger{
+		sugaredLogger: logger.Sugar(),
+		logger:        logger,
+		level:         newLevelController(zapcore.DebugLevel),
+	}
+	if opt := s.wrap(); opt != nil {
+		zl.logger = logger.WithOptions(opt)
+		zl.sugaredLogger = zl.logger.Sugar()
+		zl.unsampled = logger.Sugar()
+		zl.unsampledLogger = logger
+	}
+
+	return &ctxLogger{zl: zl}, logs
+}
+
+func TestSampling(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should log the initial entries and every thereafter-th entry after them", func(t *testing.T) {
+		l, logs := newSampledLogger(&Sampling{Initial: 2, Thereafter: 3, Interval: time.Minute})
+
+		for i := 0; i < 10; i++ {
+			l.Info(ctx, "order created")
+		}
+
+		// the 1st, 2nd, 5th and 8th entries
+		assert.Equal(t, 4, logs.FilterMessage("order created").Len())
+	})
+
+	t.Run("Should count the entries by level and message", func(t *testing.T) {
+		l, logs := newSampledLogger(&Sampling{Initial: 1, Thereafter: 100, Interval: time.Minute})
+
+		for i := 0; i < 3; i++ {
+			l.Info(ctx, "order created")
+			l.Error(ctx, "order created")
+			l.Info(ctx, "order paid")
+		}
+
+		assert.Equal(t, 3, logs.Len())
+	})
+
+	t.Run("Should call OnDropped with each dropped entry", func(t *testing.T) {
+		var dropped []string
+		l, logs := newSampledLogger(&Sampling{
+			Initial:    1,
+			Thereafter: 100,
+			Interval:   time.Minute,
+			OnDropped: func(level, message string) {
+				dropped = append(dropped, level+" "+message)
+			},
+		})
+
+		for i := 0; i < 3; i++ {
+			l.Warn(ctx, "slow query")
+		}
+
+		assert.Equal(t, 1, logs.Len())
+		assert.Equal(t, []string{"warn slow query", "warn slow query"}, dropped)
+	})
+
+	t.Run("Should not sample the entries of a NeverSample context", func(t *testing.T) {
+		l, logs := newSampledLogger(&Sampling{Initial: 1, Thereafter: 100, Interval: time.Minute})
+		never := NeverSample(ctx)
+
+		for i := 0; i < 3; i++ {
+			l.Error(never, "payment captured twice")
+			l.With("store_id", "store-1").Errorw(never, "payment captured twice")
+		}
+
+		assert.Equal(t, 6, logs.Len())
+	})
+
+	t.Run("Should log every entry when disabled", func(t *testing.T) {
+		l, logs := newSampledLogger(&Sampling{Disabled: true, Initial: 1})
+
+		for i := 0; i < 5; i++ {
+			l.Info(ctx, "order created")
+		}
+
+		assert.Nil(t, l.zl.unsampled)
+		assert.Equal(t, 5, logs.Len())
+	})
+
+	t.Run("Should sample by default only in JSON mode", func(t *testing.T) {
+		assert.NotNil(t, defaultSampling(true).wrap())
+		assert.Nil(t, defaultSampling(false).wrap())
+	})
+}
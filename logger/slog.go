@@ -0,0 +1,191 @@
+package logger
+
+import (
+	"log/slog"
+	"runtime"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/net/context"
+)
+
+// slogHandler represents an slog.Handler that writes through the core of a logger,
+// with the same fields, context fields, sampling and redaction of its entries.
+type slogHandler struct {
+	zl *zapLogger
+	// groups are the open groups, from the outermost one, the attributes added before them are fields of zl
+	groups []slogGroup
+}
+
+// slogGroup represents a group of the handler with the attributes added after it was opened.
+type slogGroup struct {
+	name  string
+	attrs []slog.Attr
+}
+
+// SlogHandler returns an slog.Handler that writes through the default instance, see Logger.Handler.
+//
+// Ex.:
+//
+//	client := library.New(library.WithLogger(slog.New(logger.SlogHandler())))
+func SlogHandler() slog.Handler {
+	return Default().Handler()
+}
+
+func (l *ctxLogger) Handler() slog.Handler {
+	return &slogHandler{zl: l.zl}
+}
+
+// slogLevel returns the zap level of the slog level, rounding down the levels between the slog ones.
+func slogLevel(level slog.Level) zapcore.Level {
+	switch {
+	case level < slog.LevelInfo:
+		return zapcore.DebugLevel
+	case level < slog.LevelWarn:
+		return zapcore.InfoLevel
+	case level < slog.LevelError:
+		return zapcore.WarnLevel
+	default:
+		return zapcore.ErrorLevel
+	}
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.zl.logger != nil && h.zl.logger.Core().Enabled(slogLevel(level))
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	ce := h.zl.check(ctx, slogLevel(r.Level), r.Message)
+	if ce == nil {
+		return nil
+	}
+
+	if !r.Time.IsZero() {
+		ce.Time = r.Time
+	}
+	if ce.Caller.Defined && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		ce.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+		ce.Caller.Function = frame.Function
+	}
+
+	fields := make([]Field, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, a)
+		return true
+	})
+
+	// the groups are nested from the innermost one, and the groups without fields are omitted
+	for i := len(h.groups) - 1; i >= 0; i-- {
+		g := h.groups[i]
+
+		inner := make([]Field, 0, len(g.attrs)+len(fields))
+		for _, a := range g.attrs {
+			inner = appendAttr(inner, a)
+		}
+		inner = append(inner, fields...)
+
+		fields = nil
+		if len(inner) > 0 {
+			fields = []Field{zap.Object(g.name, fieldsObject(inner))}
+		}
+	}
+
+	h.zl.write(ctx, ce, fields)
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	if len(h.groups) == 0 {
+		var fields []any
+		for _, a := range attrs {
+			for _, f := range appendAttr(nil, a) {
+				fields = append(fields, f)
+			}
+		}
+		if len(fields) == 0 || h.zl.sugaredLogger == nil {
+			return h
+		}
+
+		return &slogHandler{zl: h.zl.derive(func(sl *zap.SugaredLogger) *zap.SugaredLogger {
+			return sl.With(fields...)
+		})}
+	}
+
+	groups := make([]slogGroup, len(h.groups))
+	copy(groups, h.groups)
+
+	last := &groups[len(groups)-1]
+	last.attrs = append(append([]slog.Attr{}, last.attrs...), attrs...)
+
+	return &slogHandler{zl: h.zl, groups: groups}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	groups := make([]slogGroup, len(h.groups), len(h.groups)+1)
+	copy(groups, h.groups)
+
+	return &slogHandler{zl: h.zl, groups: append(groups, slogGroup{name: name})}
+}
+
+// appendAttr appends the field of the attribute to the fields, following the rules of slog:
+// empty attributes are ignored, and the attributes of groups without a key are inlined.
+func appendAttr(fields []Field, a slog.Attr) []Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	v := a.Value
+	switch v.Kind() {
+	case slog.KindString:
+		return append(fields, zap.String(a.Key, v.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(a.Key, v.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(a.Key, v.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(a.Key, v.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(a.Key, v.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(a.Key, v.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(a.Key, v.Time()))
+	case slog.KindGroup:
+		var group []Field
+		for _, ga := range v.Group() {
+			group = appendAttr(group, ga)
+		}
+		if len(group) == 0 {
+			return fields
+		}
+		if a.Key == "" {
+			return append(fields, group...)
+		}
+		return append(fields, zap.Object(a.Key, fieldsObject(group)))
+	default:
+		if err, ok := v.Any().(error); ok && err != nil {
+			return append(fields, zap.Object(a.Key, errorObject{err: err}))
+		}
+		return append(fields, zap.Any(a.Key, v.Any()))
+	}
+}
+
+// fieldsObject represents the fields of a group.
+type fieldsObject []Field
+
+func (fo fieldsObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, f := range fo {
+		f.AddTo(enc)
+	}
+	return nil
+}
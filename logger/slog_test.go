@@ -0,0 +1,175 @@
+package logger
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/net/context"
+
+	"github.com/delivery-much/dm-go/middleware"
+)
+
+type slogOrder struct {
+	id string
+}
+
+func (o slogOrder) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("id", o.id))
+}
+
+func TestSlogHandler(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "req-id")
+
+	t.Run("Should map the slog levels", func(t *testing.T) {
+		l, logs := newObservedLogger(nil)
+		sl := slog.New(l.Handler())
+
+		sl.DebugContext(ctx, "debug")
+		sl.InfoContext(ctx, "info")
+		sl.Log(ctx, slog.LevelInfo+2, "info+2")
+		sl.WarnContext(ctx, "warn")
+		sl.ErrorContext(ctx, "error")
+		sl.Log(ctx, slog.LevelError+4, "error+4")
+
+		var levels []zapcore.Level
+		for _, e := range logs.All() {
+			levels = append(levels, e.Level)
+		}
+		assert.Equal(t, []zapcore.Level{
+			zapcore.DebugLevel,
+			zapcore.InfoLevel,
+			zapcore.InfoLevel,
+			zapcore.WarnLevel,
+			zapcore.ErrorLevel,
+			zapcore.ErrorLevel,
+		}, levels)
+	})
+
+	t.Run("Should not be enabled below the level of the logger", func(t *testing.T) {
+		l, _ := newObservedLogger(nil)
+		assert.Nil(t, l.SetLevel(WARN, 0))
+		h := l.Handler()
+
+		assert.False(t, h.Enabled(ctx, slog.LevelInfo))
+		assert.True(t, h.Enabled(ctx, slog.LevelWarn))
+	})
+
+	t.Run("Should log the attributes with the context fields", func(t *testing.T) {
+		l, logs := newObservedLogger(map[any]string{"store": "store_id"})
+		sl := slog.New(l.Handler())
+		at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		sl.InfoContext(WithFields(context.WithValue(ctx, "store", "store-1"), "order_id", "order-1"), "order created",
+			slog.Int("items", 3),
+			slog.Uint64("total", 1500),
+			slog.Float64("discount", 0.5),
+			slog.Bool("paid", true),
+			slog.Duration("elapsed", time.Second),
+			slog.Time("paid_at", at),
+			slog.Any("order", slogOrder{id: "order-1"}),
+			slog.Any("error", errors.New("timeout")),
+		)
+
+		entries := logs.All()
+		assert.Len(t, entries, 1)
+		assert.Equal(t, "order created", entries[0].Message)
+		assert.Equal(t, map[string]any{
+			"request_id": "req-id",
+			"store_id":   "store-1",
+			"order_id":   "order-1",
+			"items":      int64(3),
+			"total":      uint64(1500),
+			"discount":   0.5,
+			"paid":       true,
+			"elapsed":    time.Second,
+			"paid_at":    at,
+			"order":      map[string]any{"id": "order-1"},
+			"error":      map[string]any{"message": "timeout", "type": "*errors.errorString"},
+		}, entries[0].ContextMap())
+	})
+
+	t.Run("Should nest the attributes of the groups", func(t *testing.T) {
+		l, logs := newObservedLogger(nil)
+		sl := slog.New(l.Handler()).
+			With("service", "orders").
+			WithGroup("http").
+			With("method", "POST").
+			WithGroup("response")
+
+		sl.InfoContext(ctx, "request handled", "status", 201, slog.Group("", slog.String("inlined", "yes")))
+
+		assert.Equal(t, map[string]any{
+			"request_id": "req-id",
+			"service":    "orders",
+			"http": map[string]any{
+				"method": "POST",
+				"response": map[string]any{
+					"status":  int64(201),
+					"inlined": "yes",
+				},
+			},
+		}, logs.All()[0].ContextMap())
+	})
+
+	t.Run("Should omit the groups without attributes and the empty attributes", func(t *testing.T) {
+		l, logs := newObservedLogger(nil)
+		sl := slog.New(l.Handler()).With("service", "orders").WithGroup("http")
+
+		sl.InfoContext(ctx, "request handled", slog.Attr{}, slog.Group("empty"))
+
+		assert.Equal(t, map[string]any{
+			"request_id": "req-id",
+			"service":    "orders",
+		}, logs.All()[0].ContextMap())
+	})
+
+	t.Run("Should use the time of the record", func(t *testing.T) {
+		l, logs := newObservedLogger(nil)
+		at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		r := slog.NewRecord(at, slog.LevelInfo, "order created", 0)
+		assert.Nil(t, l.Handler().Handle(ctx, r))
+
+		assert.Equal(t, at, logs.All()[0].Time)
+	})
+
+	t.Run("Should log the base fields in the format of the logger", func(t *testing.T) {
+		l, out := newBufferedLogger(t, Configuration{IsJSON: true, BaseFields: BaseFields{ServiceName: "orders"}})
+
+		slog.New(l.Handler()).InfoContext(ctx, "order created", "order_id", "order-1")
+
+		entry := decodeEntry(t, out)
+		assert.Equal(t, "order created", entry["message"])
+		assert.Equal(t, "info", entry["level"])
+		assert.Equal(t, "orders", entry["service_name"])
+		assert.Equal(t, "req-id", entry["request_id"])
+		assert.Equal(t, "order-1", entry["order_id"])
+	})
+
+	t.Run("Should set the handler of slog.Default", func(t *testing.T) {
+		previous := slog.Default()
+		t.Cleanup(func() {
+			slog.SetDefault(previous)
+			log = nil
+		})
+
+		assert.Nil(t, NewLogger(Configuration{IsJSON: true, SlogDefault: true}))
+
+		handler, ok := slog.Default().Handler().(*slogHandler)
+		assert.True(t, ok)
+		assert.Same(t, log.zl, handler.zl)
+	})
+
+	t.Run("Should not panic without a logger", func(t *testing.T) {
+		h := (&ctxLogger{zl: &zapLogger{}}).Handler()
+
+		assert.NotPanics(t, func() {
+			slog.New(h).With("service", "orders").InfoContext(ctx, "order created")
+		})
+		assert.False(t, h.Enabled(ctx, slog.LevelError))
+	})
+}
@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"encoding/hex"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+)
+
+const (
+	defaultTraceIDField = "trace_id"
+	defaultSpanIDField  = "span_id"
+	defaultSampledField = "trace_sampled"
+)
+
+// TraceFields represents the fields of the OpenTelemetry trace context added to the entries,
+// so the logs can be joined with the traces.
+// The zero value adds the trace_id, span_id and trace_sampled fields.
+//
+// Ex.:
+//
+//	// fields expected by Datadog
+//	TraceFields{
+//		TraceID: "dd.trace_id",
+//		SpanID:  "dd.span_id",
+//	}
+type TraceFields struct {
+	// Disabled does not add the trace context to the entries.
+	Disabled bool
+	// TraceID, SpanID and Sampled are the names of the fields of the trace id, the span id and the sampled flag.
+	// Default: trace_id, span_id and trace_sampled.
+	TraceID string
+	SpanID  string
+	Sampled string
+}
+
+// withDefaults returns the trace fields with the default names of the fields not set.
+func (tf TraceFields) withDefaults() TraceFields {
+	if tf.TraceID == "" {
+		tf.TraceID = defaultTraceIDField
+	}
+	if tf.SpanID == "" {
+		tf.SpanID = defaultSpanIDField
+	}
+	if tf.Sampled == "" {
+		tf.Sampled = defaultSampledField
+	}
+
+	return tf
+}
+
+// fields returns the typed fields of the span in the context, or false when the context has no valid span or they are disabled.
+func (tf TraceFields) fields(ctx context.Context) ([3]Field, bool) {
+	if tf.Disabled {
+		return [3]Field{}, false
+	}
+
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return [3]Field{}, false
+	}
+
+	// the ids are encoded in a single string, so the fields cost one allocation
+	traceID, spanID := sc.TraceID(), sc.SpanID()
+	var buf [2*len(traceID) + 2*len(spanID)]byte
+	hex.Encode(buf[:], traceID[:])
+	hex.Encode(buf[2*len(traceID):], spanID[:])
+	ids := string(buf[:])
+
+	tf = tf.withDefaults()
+	return [3]Field{
+		zap.String(tf.TraceID, ids[:2*len(traceID)]),
+		zap.String(tf.SpanID, ids[2*len(traceID):]),
+		zap.Bool(tf.Sampled, sc.IsSampled()),
+	}, true
+}
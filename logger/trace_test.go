@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/context"
+)
+
+func TestTraceFields(t *testing.T) {
+	traceID, _ := oteltrace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := oteltrace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := oteltrace.ContextWithSpanContext(context.Background(), oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: oteltrace.FlagsSampled,
+	}))
+
+	t.Run("Should log the trace id, the span id and the sampled flag of the span in the context", func(t *testing.T) {
+		l, logs := newObservedLogger(nil)
+
+		l.Info(ctx, "traced")
+
+		assert.Equal(t, map[string]any{
+			"trace_id":      "4bf92f3577b34da6a3ce929d0e0e4736",
+			"span_id":       "00f067aa0ba902b7",
+			"trace_sampled": true,
+		}, logs.All()[0].ContextMap())
+	})
+	t.Run("Should use the configured field names", func(t *testing.T) {
+		l, logs := newObservedLogger(nil)
+		l.zl.traceFields = TraceFields{TraceID: "dd.trace_id", SpanID: "dd.span_id"}
+
+		l.Info(ctx, "traced")
+
+		assert.Equal(t, map[string]any{
+			"dd.trace_id":   "4bf92f3577b34da6a3ce929d0e0e4736",
+			"dd.span_id":    "00f067aa0ba902b7",
+			"trace_sampled": true,
+		}, logs.All()[0].ContextMap())
+	})
+	t.Run("Should not log the trace context when disabled or when there is no span", func(t *testing.T) {
+		l, logs := newObservedLogger(nil)
+
+		l.Info(context.Background(), "not traced")
+		l.zl.traceFields = TraceFields{Disabled: true}
+		l.Info(ctx, "disabled")
+
+		assert.Empty(t, logs.All()[0].ContextMap())
+		assert.Empty(t, logs.All()[1].ContextMap())
+	})
+}
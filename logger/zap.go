@@ -13,7 +13,16 @@ const requestIDField = "request_id"
 
 type zapLogger struct {
 	sugaredLogger *zap.SugaredLogger
-	ctxFields     map[any]string
+	// logger is the structured logger of the same core, for the typed fields, see Field
+	logger *zap.Logger
+	// unsampled is the logger without sampling, for the entries that must not be sampled, nil if there is no sampling
+	unsampled       *zap.SugaredLogger
+	unsampledLogger *zap.Logger
+	ctxFields       []ctxKey
+	traceFields     TraceFields
+	level           *levelController
+	// files are the rotating files of the outputs, shared with the children and closed by Close
+	files []*rotatingFile
 }
 
 func getZapLevel(level string) zapcore.Level {
@@ -37,73 +46,161 @@ func getZapLevel(level string) zapcore.Level {
 // isJSON for JSON output and production config and encoder
 // serviceName for set the default field service name in logger
 func newZapLogger(config Configuration) (*zapLogger, error) {
-	var c zap.Config
+	level := newLevelController(getZapLevel(config.Level))
+	if !config.IsJSON && config.Level == "" {
+		// keeps the development default of logging everything
+		level = newLevelController(zapcore.DebugLevel)
+	}
 
-	if config.IsJSON {
-		encoderConfig := zap.NewProductionEncoderConfig()
-		encoderConfig.MessageKey = "message"
-		encoderConfig.TimeKey = "time"
-		encoderConfig.StacktraceKey = "stack"
-		encoderConfig.EncodeTime = zapcore.RFC3339TimeEncoder
+	stackLevel, withStack, err := stacktraceLevel(config)
+	if err != nil {
+		return nil, err
+	}
 
-		c = zap.NewProductionConfig()
-		c.DisableCaller = true
-		c.InitialFields = getBaseFields(config.BaseFields)
-		c.EncoderConfig = encoderConfig
-		c.Level = zap.NewAtomicLevelAt(getZapLevel(config.Level))
-	} else {
-		c = zap.NewDevelopmentConfig()
-		c.EncoderConfig = zap.NewDevelopmentEncoderConfig()
-		c.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	// the redaction wraps each output, since it is applied when the entries are written
+	redact := func(core zapcore.Core) zapcore.Core { return core }
+	if rd := newRedactor(config.Redaction); rd != nil {
+		redact = func(core zapcore.Core) zapcore.Core {
+			return &redactCore{Core: core, rd: rd}
+		}
 	}
 
-	logger, err := c.Build()
+	var logger *zap.Logger
+	var files []*rotatingFile
+	if len(config.Outputs) > 0 {
+		logger, files, err = newOutputsLogger(config, level, redact)
+	} else {
+		logger, err = newConfigLogger(config, level)
+		if err == nil {
+			logger = logger.WithOptions(zap.WrapCore(redact))
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
+
+	if withStack {
+		logger = logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return &stackCore{Core: core, level: stackLevel}
+		}))
+	}
 	defer logger.Sync()
 
+	if config.Sampling == nil {
+		config.Sampling = defaultSampling(config.IsJSON)
+	}
+	unsampled := logger
+	if sampler := config.Sampling.wrap(); sampler != nil {
+		logger = logger.WithOptions(sampler)
+	} else {
+		unsampled = nil
+	}
+
 	if config.CTXFields == nil {
 		config.CTXFields = map[any]string{}
 	}
 
 	config.CTXFields[middlewareLib.RequestIDKey] = requestIDField
 
-	return &zapLogger{
+	zl := &zapLogger{
 		sugaredLogger: logger.Sugar(),
-		ctxFields:     config.CTXFields,
-	}, nil
+		logger:        logger,
+		ctxFields:     newCTXKeys(config.CTXFields),
+		traceFields:   config.TraceFields,
+		level:         level,
+		files:         files,
+	}
+	if unsampled != nil {
+		zl.unsampled = unsampled.Sugar()
+		zl.unsampledLogger = unsampled
+	}
+
+	return zl, nil
+}
+
+// newConfigLogger returns a zap logger that writes to the standard error, with the prod or dev config.
+func newConfigLogger(config Configuration, level *levelController) (*zap.Logger, error) {
+	var c zap.Config
+	if config.IsJSON {
+		c = zap.NewProductionConfig()
+		c.DisableCaller = true
+		c.InitialFields = getBaseFields(config.BaseFields)
+		c.EncoderConfig = jsonEncoderConfig()
+	} else {
+		c = zap.NewDevelopmentConfig()
+		c.EncoderConfig = consoleEncoderConfig()
+	}
+	c.Level = level.atomic
+	// sampled and with stack traces after the build, see Sampling and stackCore
+	c.Sampling = nil
+	c.DisableStacktrace = true
+
+	return c.Build()
+}
+
+// derive returns a copy of the logger with the function applied to its zap loggers.
+func (l zapLogger) derive(fn func(*zap.SugaredLogger) *zap.SugaredLogger) *zapLogger {
+	l.sugaredLogger = fn(l.sugaredLogger)
+	l.logger = l.sugaredLogger.Desugar()
+	if l.unsampled != nil {
+		l.unsampled = fn(l.unsampled)
+		l.unsampledLogger = l.unsampled.Desugar()
+	}
+
+	return &l
 }
 
 // addCTXFields adds context related information in a given logger,
+// the configured context fields, the trace context and the fields carried by the context, see WithFields,
 // and then returns a pointer to a copy of the original logger with the new information
 func (l zapLogger) addCTXFields(ctx context.Context) (zl *zapLogger) {
-	if l.sugaredLogger == nil || l.ctxFields == nil {
+	zl = &l
+	if l.sugaredLogger == nil || ctx == nil {
 		return
 	}
-
-	zl = &l
+	if l.unsampled != nil && neverSample(ctx) {
+		l.sugaredLogger = l.unsampled
+	}
 
 	// add context values to log
-	fieldsAndVals := []any{}
-	for key, field := range l.ctxFields {
-		val := ctx.Value(key)
+	fields := []ctxField{}
+	for _, k := range l.ctxFields {
+		val := ctx.Value(k.key)
 		if val == nil {
 			continue
 		}
 
-		fieldsAndVals = append(fieldsAndVals, field)
-		fieldsAndVals = append(fieldsAndVals, val)
+		fields = setField(fields, ctxField{key: k.field, value: val})
+	}
+	if trace, ok := l.traceFields.fields(ctx); ok {
+		for _, f := range trace {
+			fields = setField(fields, ctxField{key: f.Key, value: fieldValue(f)})
+		}
+	}
+	for _, f := range contextFields(ctx) {
+		fields = setField(fields, f)
+	}
+	if len(fields) == 0 {
+		return
+	}
+
+	fieldsAndVals := make([]any, 0, len(fields)*2)
+	for _, f := range fields {
+		fieldsAndVals = append(fieldsAndVals, f.key, f.value)
 	}
 
 	l.sugaredLogger = l.sugaredLogger.With(fieldsAndVals...)
 	return
 }
 
-func (l *zapLogger) AddRequestID(requestID string) *zapLogger {
-	return &zapLogger{
-		sugaredLogger: l.sugaredLogger.With(requestIDField, requestID),
+func (l *zapLogger) AddRequestID(requestID string) NoCTXLogger {
+	if l.sugaredLogger == nil {
+		return l
 	}
+
+	return l.derive(func(sl *zap.SugaredLogger) *zap.SugaredLogger {
+		return sl.With(requestIDField, requestID)
+	})
 }
 
 func (l *zapLogger) Debug(msg string) {
@@ -234,6 +234,39 @@ func StartTrackEntrypoint(ctx context.Context, n string) (context.Context, func(
 	}
 }
 
+// StartTrackConsumer is used to start a consumer span in the application trace, for messages received from a broker.
+//
+// It works like StartTrackEntrypoint, returning the new context that should be used when handling the message,
+// but the span is only started if the OpenTelemetry connection was started, otherwise the context is returned unchanged.
+//
+// Example:
+//
+//	ctx, endFunction := StartTrackConsumer(ctx, "orders process", attribute.String("messaging.system", "rabbitmq"))
+//	defer endFunction()
+func StartTrackConsumer(ctx context.Context, n string, attrs ...attribute.KeyValue) (context.Context, func()) {
+	return startTrackKind(ctx, n, oteltrace.SpanKindConsumer, attrs)
+}
+
+// StartTrackProducer is used to start a producer span in the application trace, for messages sent to a broker.
+//
+// It works like StartTrackConsumer, and the returned context should be used to inject the trace context in the message.
+func StartTrackProducer(ctx context.Context, n string, attrs ...attribute.KeyValue) (context.Context, func()) {
+	return startTrackKind(ctx, n, oteltrace.SpanKindProducer, attrs)
+}
+
+func startTrackKind(ctx context.Context, n string, kind oteltrace.SpanKind, attrs []attribute.KeyValue) (context.Context, func()) {
+	if globalTracer == nil {
+		return ctx, func() {}
+	}
+
+	ctx, span := globalTracer.Start(ctx, n, oteltrace.WithSpanKind(kind), oteltrace.WithAttributes(attrs...))
+	addCTXTraceAttributes(ctx, span)
+
+	return ctx, func() {
+		span.End()
+	}
+}
+
 // NewMongoMonitor returns a new *event.CommandMonitor for the mongodb client
 //
 // # The returned event monitor is meant to be set in the mongoDB clientOptions through the mongo.NewMonitor function
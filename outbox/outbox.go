@@ -0,0 +1,161 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/delivery-much/dm-go/middleware"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	// StatusPending is the status of the events waiting to be published.
+	StatusPending = "pending"
+	// StatusSent is the status of the events already published and confirmed by the broker.
+	StatusSent = "sent"
+
+	// requestIDHeader is the event header that stores the request id of the context the event was stored with.
+	requestIDHeader = "x-request-id"
+)
+
+// Message represents an event to be published through the outbox.
+type Message struct {
+	Exchange    string
+	RoutingKey  string
+	ContentType string
+	Headers     map[string]any
+	Body        []byte
+}
+
+// Event represents an event stored in the outbox collection.
+type Event struct {
+	ID          primitive.ObjectID `bson:"_id"`
+	Exchange    string             `bson:"exchange"`
+	RoutingKey  string             `bson:"routing_key"`
+	ContentType string             `bson:"content_type,omitempty"`
+	Headers     map[string]any     `bson:"headers,omitempty"`
+	Body        []byte             `bson:"body"`
+	// TraceContext stores the trace context and the request id of the context the event was stored with,
+	// so the publish continues the same trace.
+	TraceContext  map[string]string `bson:"trace_context,omitempty"`
+	Status        string            `bson:"status"`
+	Attempts      int               `bson:"attempts"`
+	LastError     string            `bson:"last_error,omitempty"`
+	NextAttemptAt time.Time         `bson:"next_attempt_at"`
+	CreatedAt     time.Time         `bson:"created_at"`
+	SentAt        *time.Time        `bson:"sent_at,omitempty"`
+	// ExpireAt is when the sent event is removed from the collection by its TTL index, see RelayConfig.SentRetention.
+	ExpireAt *time.Time `bson:"expire_at,omitempty"`
+}
+
+// Outbox represents the outbox collection, where the events are stored before being published by the Relay.
+type Outbox struct {
+	coll *mongo.Collection
+}
+
+// New returns a new Outbox that stores the events in the given collection,
+// creating the index used by the Relay to find the pending events
+// and the TTL index that removes the sent events after their retention, see RelayConfig.SentRetention.
+func New(ctx context.Context, coll *mongo.Collection) (*Outbox, error) {
+	_, err := coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "status", Value: 1},
+				{Key: "next_attempt_at", Value: 1},
+			},
+		},
+		{
+			// the pending events have no expire_at, so they are never removed
+			Keys:    bson.D{{Key: "expire_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Outbox{coll: coll}, nil
+}
+
+// Store inserts the messages in the outbox collection, to be published by the Relay.
+//
+// To store the messages in the same transaction of the business writes,
+// call Store with the session context of the transaction.
+//
+// Ex.:
+//
+//	_, err := session.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+//		_, err := orders.InsertOne(sc, order)
+//		if err != nil {
+//			return nil, err
+//		}
+//
+//		return nil, ob.Store(sc, outbox.Message{
+//			Exchange:    "orders",
+//			RoutingKey:  "order.created",
+//			ContentType: "application/json",
+//			Body:        body,
+//		})
+//	})
+func (o *Outbox) Store(ctx context.Context, messages ...Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	traceContext := captureTraceContext(ctx)
+
+	docs := make([]any, 0, len(messages))
+	for _, m := range messages {
+		docs = append(docs, Event{
+			ID:            primitive.NewObjectID(),
+			Exchange:      m.Exchange,
+			RoutingKey:    m.RoutingKey,
+			ContentType:   m.ContentType,
+			Headers:       m.Headers,
+			Body:          m.Body,
+			TraceContext:  traceContext,
+			Status:        StatusPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+
+	_, err := o.coll.InsertMany(ctx, docs)
+	return err
+}
+
+// captureTraceContext returns the trace context and the request id of the context.
+func captureTraceContext(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+
+	if reqID := middleware.GetReqID(ctx); reqID != "" {
+		carrier[requestIDHeader] = reqID
+	}
+
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// restoreTraceContext returns a copy of the context with the trace context and the request id captured in the event.
+func restoreTraceContext(ctx context.Context, traceContext map[string]string) context.Context {
+	if len(traceContext) == 0 {
+		return ctx
+	}
+
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(traceContext))
+
+	if reqID := traceContext[requestIDHeader]; reqID != "" {
+		ctx = context.WithValue(ctx, middleware.RequestIDKey, reqID)
+	}
+
+	return ctx
+}
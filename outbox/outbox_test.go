@@ -0,0 +1,260 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/delivery-much/dm-go/middleware"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceContext(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	traceID, _ := oteltrace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := oteltrace.SpanIDFromHex("00f067aa0ba902b7")
+	sc := oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: oteltrace.FlagsSampled,
+	})
+
+	t.Run("Should capture and restore the trace context and the request id", func(t *testing.T) {
+		ctx := oteltrace.ContextWithSpanContext(context.Background(), sc)
+		ctx = context.WithValue(ctx, middleware.RequestIDKey, "req-id")
+
+		traceContext := captureTraceContext(ctx)
+		restored := restoreTraceContext(context.Background(), traceContext)
+
+		assert.Equal(t, "req-id", traceContext[requestIDHeader])
+		assert.Equal(t, traceID, oteltrace.SpanContextFromContext(restored).TraceID())
+		assert.Equal(t, "req-id", middleware.GetReqID(restored))
+	})
+	t.Run("Should not capture anything from an empty context", func(t *testing.T) {
+		traceContext := captureTraceContext(context.Background())
+
+		assert.Nil(t, traceContext)
+		assert.Equal(t, context.Background(), restoreTraceContext(context.Background(), traceContext))
+	})
+}
+
+func TestRelayBackoff(t *testing.T) {
+	r := NewRelay(nil, nil, RelayConfig{
+		InitialBackoff: time.Second,
+		MaxBackoff:     10 * time.Second,
+	})
+
+	t.Run("Should double the backoff at each attempt", func(t *testing.T) {
+		assert.Equal(t, time.Second, r.backoff(1))
+		assert.Equal(t, 2*time.Second, r.backoff(2))
+		assert.Equal(t, 8*time.Second, r.backoff(4))
+	})
+	t.Run("Should limit the backoff to the max backoff", func(t *testing.T) {
+		assert.Equal(t, 10*time.Second, r.backoff(5))
+		assert.Equal(t, 10*time.Second, r.backoff(100))
+	})
+}
+
+func TestNewRelay(t *testing.T) {
+	t.Run("Should use the default configuration", func(t *testing.T) {
+		r := NewRelay(nil, nil, RelayConfig{})
+
+		assert.Equal(t, RelayConfig{
+			BatchSize:      defaultBatchSize,
+			PollInterval:   defaultPollInterval,
+			ClaimTimeout:   defaultClaimTimeout,
+			InitialBackoff: defaultInitialBackoff,
+			MaxBackoff:     defaultMaxBackoff,
+			SentRetention:  defaultSentRetention,
+		}, r.config)
+	})
+}
+
+func TestAMQPHeaders(t *testing.T) {
+	t.Run("Should convert the headers decoded from the collection to amqp types", func(t *testing.T) {
+		at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		id := primitive.NewObjectID()
+
+		raw, err := bson.Marshal(Event{
+			ID: id,
+			Headers: map[string]any{
+				"x-tenant":  "store-1",
+				"x-retries": 2,
+				"x-sent-at": at,
+				"x-origin":  map[string]any{"service": "orders", "created_at": at},
+				"x-tags":    []any{"promo", map[string]any{"id": id}},
+			},
+		})
+		assert.Nil(t, err)
+
+		var evt Event
+		assert.Nil(t, bson.Unmarshal(raw, &evt))
+		assert.NotNil(t, amqp.Table(evt.Headers).Validate())
+
+		headers := amqpHeaders(evt.Headers)
+		assert.Nil(t, headers.Validate())
+		assert.Equal(t, amqp.Table{
+			"x-tenant":  "store-1",
+			"x-retries": int32(2),
+			"x-sent-at": at.Local(),
+			"x-origin":  amqp.Table{"service": "orders", "created_at": at.Local()},
+			"x-tags":    []any{"promo", amqp.Table{"id": id.Hex()}},
+		}, headers)
+	})
+
+	t.Run("Should keep nil headers", func(t *testing.T) {
+		assert.Nil(t, amqpHeaders(nil))
+	})
+}
+
+type publisherStub struct {
+	published []amqp.Publishing
+	err       error
+}
+
+func (p *publisherStub) PublishWithConfirm(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, msg)
+	return nil
+}
+
+// command returns the next command sent to the mocked deployment.
+func command(mt *mtest.T) (string, bson.M) {
+	evt := mt.GetStartedEvent()
+	if evt == nil {
+		mt.Fatal("no command sent")
+	}
+
+	var cmd bson.M
+	assert.Nil(mt, bson.Unmarshal(evt.Command, &cmd))
+	return evt.CommandName, cmd
+}
+
+func TestOutbox(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Should create the index of the pending events and the TTL index of the sent events", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		_, err := New(context.Background(), mt.Coll)
+		assert.Nil(mt, err)
+
+		name, cmd := command(mt)
+		assert.Equal(mt, "createIndexes", name)
+		indexes := cmd["indexes"].(bson.A)
+		assert.Len(mt, indexes, 2)
+		assert.Equal(mt, bson.M{"status": int32(1), "next_attempt_at": int32(1)}, indexes[0].(bson.M)["key"])
+		assert.Equal(mt, bson.M{"expire_at": int32(1)}, indexes[1].(bson.M)["key"])
+		assert.Equal(mt, int32(0), indexes[1].(bson.M)["expireAfterSeconds"])
+	})
+
+	mt.Run("Should store the messages as pending events with the request id of the context", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		ob := &Outbox{coll: mt.Coll}
+		ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "req-id")
+
+		err := ob.Store(ctx,
+			Message{Exchange: "orders", RoutingKey: "order.created", Body: []byte("1")},
+			Message{Exchange: "orders", RoutingKey: "order.paid", Headers: map[string]any{"x-tenant": "store-1"}, Body: []byte("2")},
+		)
+		assert.Nil(mt, err)
+
+		name, cmd := command(mt)
+		assert.Equal(mt, "insert", name)
+		docs := cmd["documents"].(bson.A)
+		assert.Len(mt, docs, 2)
+
+		first := docs[0].(bson.M)
+		assert.Equal(mt, "order.created", first["routing_key"])
+		assert.Equal(mt, StatusPending, first["status"])
+		assert.Equal(mt, bson.M{requestIDHeader: "req-id"}, first["trace_context"])
+		assert.NotContains(mt, first, "sent_at")
+		assert.NotContains(mt, first, "expire_at")
+		assert.Equal(mt, bson.M{"x-tenant": "store-1"}, docs[1].(bson.M)["headers"])
+	})
+
+	mt.Run("Should not store without messages", func(mt *mtest.T) {
+		assert.Nil(mt, (&Outbox{coll: mt.Coll}).Store(context.Background()))
+		assert.Nil(mt, mt.GetStartedEvent())
+	})
+
+	mt.Run("Should publish the pending events and mark them as sent with their expiration", func(mt *mtest.T) {
+		id := primitive.NewObjectID()
+		createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+				{Key: "_id", Value: id},
+				{Key: "exchange", Value: "orders"},
+				{Key: "routing_key", Value: "order.created"},
+				{Key: "headers", Value: bson.D{{Key: "x-origin", Value: bson.D{{Key: "service", Value: "orders"}}}}},
+				{Key: "body", Value: []byte("1")},
+				{Key: "status", Value: StatusPending},
+				{Key: "created_at", Value: createdAt},
+			}}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}),
+		)
+		p := &publisherStub{}
+		r := NewRelay(&Outbox{coll: mt.Coll}, p, RelayConfig{SentRetention: time.Hour})
+
+		assert.Nil(mt, r.PublishPending(context.Background()))
+
+		assert.Len(mt, p.published, 1)
+		assert.Equal(mt, id.Hex(), p.published[0].MessageId)
+		assert.Equal(mt, amqp.Persistent, p.published[0].DeliveryMode)
+		assert.Equal(mt, amqp.Table{"x-origin": amqp.Table{"service": "orders"}}, p.published[0].Headers)
+
+		name, cmd := command(mt)
+		assert.Equal(mt, "findAndModify", name)
+		assert.Equal(mt, StatusPending, cmd["query"].(bson.M)["status"])
+		assert.Contains(mt, cmd["update"].(bson.M)["$set"], "next_attempt_at")
+
+		name, cmd = command(mt)
+		assert.Equal(mt, "update", name)
+		update := cmd["updates"].(bson.A)[0].(bson.M)
+		assert.Equal(mt, id, update["q"].(bson.M)["_id"])
+		set := update["u"].(bson.M)["$set"].(bson.M)
+		assert.Equal(mt, StatusSent, set["status"])
+		sentAt := set["sent_at"].(primitive.DateTime).Time()
+		expireAt := set["expire_at"].(primitive.DateTime).Time()
+		assert.Equal(mt, time.Hour, expireAt.Sub(sentAt))
+
+		name, _ = command(mt)
+		assert.Equal(mt, "findAndModify", name)
+	})
+
+	mt.Run("Should schedule the events that fail to be published to be retried", func(mt *mtest.T) {
+		id := primitive.NewObjectID()
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+				{Key: "_id", Value: id},
+				{Key: "exchange", Value: "orders"},
+				{Key: "status", Value: StatusPending},
+				{Key: "attempts", Value: 2},
+			}}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}),
+		)
+		r := NewRelay(&Outbox{coll: mt.Coll}, &publisherStub{err: errors.New("broker unavailable")}, RelayConfig{})
+
+		assert.Nil(mt, r.PublishPending(context.Background()))
+
+		command(mt)
+		_, cmd := command(mt)
+		u := cmd["updates"].(bson.A)[0].(bson.M)["u"].(bson.M)
+		assert.Equal(mt, "broker unavailable", u["$set"].(bson.M)["last_error"])
+		assert.NotContains(mt, u["$set"], "status")
+		assert.Equal(mt, bson.M{"attempts": int32(1)}, u["$inc"])
+	})
+}
@@ -0,0 +1,256 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/delivery-much/dm-go/logger"
+	"github.com/streadway/amqp"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultBatchSize      = 100
+	defaultPollInterval   = time.Second
+	defaultClaimTimeout   = time.Minute
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = 5 * time.Minute
+	defaultSentRetention  = 7 * 24 * time.Hour
+)
+
+// Publisher represents the publisher of the events, that waits for the broker to confirm each message.
+//
+// The rabbitmq.RabbitMQ interface implements it.
+type Publisher interface {
+	PublishWithConfirm(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) error
+}
+
+// RelayConfig represents the configuration of the Relay.
+type RelayConfig struct {
+	// BatchSize is the maximum number of events published in each poll. Default: 100.
+	BatchSize int
+	// PollInterval is the interval between the polls of pending events. Default: 1 second.
+	PollInterval time.Duration
+	// ClaimTimeout is how long an event claimed by a relay is hidden from the other relays
+	// while it is being published. Default: 1 minute.
+	ClaimTimeout time.Duration
+	// InitialBackoff and MaxBackoff are the bounds of the exponential delay before retrying to publish an event.
+	// Default: 1 second and 5 minutes.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// SentRetention is how long the sent events are kept in the collection before the TTL index removes them. Default: 7 days.
+	SentRetention time.Duration
+}
+
+// Relay represents the worker that publishes the pending events of the outbox.
+//
+// Many relays can run at the same time, in different instances of the application,
+// since each event is claimed by a single relay before being published.
+// Events are published at least once: if the application crashes after publishing an event
+// and before marking it as sent, it is published again after the ClaimTimeout.
+type Relay struct {
+	outbox    *Outbox
+	publisher Publisher
+	config    RelayConfig
+}
+
+// NewRelay returns a new Relay that publishes the events of the outbox through the publisher.
+func NewRelay(o *Outbox, p Publisher, config RelayConfig) *Relay {
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultBatchSize
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultPollInterval
+	}
+	if config.ClaimTimeout <= 0 {
+		config.ClaimTimeout = defaultClaimTimeout
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = defaultInitialBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultMaxBackoff
+	}
+	if config.SentRetention <= 0 {
+		config.SentRetention = defaultSentRetention
+	}
+
+	return &Relay{
+		outbox:    o,
+		publisher: p,
+		config:    config,
+	}
+}
+
+// Run publishes the pending events every PollInterval, until the context is done.
+//
+// Ex.:
+//
+//	go relay.Run(ctx)
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		err := r.PublishPending(ctx)
+		if err != nil && ctx.Err() == nil {
+			logger.Errorw(ctx, "Failed to publish the pending outbox events", "error", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PublishPending claims and publishes up to BatchSize pending events.
+// Events that fail to be published are retried with an exponential backoff.
+func (r *Relay) PublishPending(ctx context.Context) error {
+	for i := 0; i < r.config.BatchSize; i++ {
+		evt, err := r.claim(ctx)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = r.publish(ctx, evt)
+		if err != nil {
+			logger.Warnw(ctx, "Failed to publish outbox event",
+				"event_id", evt.ID.Hex(),
+				"exchange", evt.Exchange,
+				"routing_key", evt.RoutingKey,
+				"attempts", evt.Attempts+1,
+				"error", err.Error(),
+			)
+			err = r.markFailed(ctx, evt, err)
+		} else {
+			err = r.markSent(ctx, evt)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// claim finds the oldest pending event that is due, hiding it from the other relays for the ClaimTimeout.
+func (r *Relay) claim(ctx context.Context) (evt Event, err error) {
+	now := time.Now()
+	err = r.outbox.coll.FindOneAndUpdate(ctx,
+		bson.M{
+			"status":          StatusPending,
+			"next_attempt_at": bson.M{"$lte": now},
+		},
+		bson.M{"$set": bson.M{"next_attempt_at": now.Add(r.config.ClaimTimeout)}},
+		options.FindOneAndUpdate().SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}),
+	).Decode(&evt)
+	return
+}
+
+func (r *Relay) publish(ctx context.Context, evt Event) error {
+	return r.publisher.PublishWithConfirm(restoreTraceContext(ctx, evt.TraceContext), evt.Exchange, evt.RoutingKey, amqp.Publishing{
+		Headers:      amqpHeaders(evt.Headers),
+		ContentType:  evt.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    evt.ID.Hex(),
+		Timestamp:    evt.CreatedAt,
+		Body:         evt.Body,
+	})
+}
+
+// amqpHeaders returns the headers of the event with the types decoded by the driver converted to the amqp ones,
+// since amqp rejects the nested documents, arrays and dates as they come back from the collection.
+func amqpHeaders(headers map[string]any) amqp.Table {
+	if headers == nil {
+		return nil
+	}
+
+	table := make(amqp.Table, len(headers))
+	for k, v := range headers {
+		table[k] = amqpValue(v)
+	}
+	return table
+}
+
+// amqpValue returns the value converted to a type accepted by amqp, or its string representation when there is none.
+func amqpValue(v any) any {
+	switch v := v.(type) {
+	case nil, bool, byte, int, int16, int32, int64, float32, float64, string, []byte, time.Time, amqp.Decimal:
+		return v
+	case amqp.Table:
+		return amqpHeaders(v)
+	case map[string]any:
+		return amqpHeaders(v)
+	case primitive.M:
+		return amqpHeaders(v)
+	case primitive.D:
+		table := make(amqp.Table, len(v))
+		for _, e := range v {
+			table[e.Key] = amqpValue(e.Value)
+		}
+		return table
+	case primitive.A:
+		return amqpValue([]any(v))
+	case []any:
+		values := make([]any, len(v))
+		for i := range v {
+			values[i] = amqpValue(v[i])
+		}
+		return values
+	case primitive.DateTime:
+		return v.Time()
+	case primitive.ObjectID:
+		return v.Hex()
+	case primitive.Binary:
+		return v.Data
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// markSent marks the event as sent, to be removed by the TTL index after the SentRetention.
+func (r *Relay) markSent(ctx context.Context, evt Event) error {
+	now := time.Now()
+	_, err := r.outbox.coll.UpdateByID(ctx, evt.ID, bson.M{
+		"$set": bson.M{
+			"status":    StatusSent,
+			"sent_at":   now,
+			"expire_at": now.Add(r.config.SentRetention),
+		},
+	})
+	return err
+}
+
+func (r *Relay) markFailed(ctx context.Context, evt Event, publishErr error) error {
+	_, err := r.outbox.coll.UpdateByID(ctx, evt.ID, bson.M{
+		"$set": bson.M{
+			"next_attempt_at": time.Now().Add(r.backoff(evt.Attempts + 1)),
+			"last_error":      publishErr.Error(),
+		},
+		"$inc": bson.M{"attempts": 1},
+	})
+	return err
+}
+
+// backoff returns the delay before the given attempt, doubling the InitialBackoff at each attempt up to the MaxBackoff.
+func (r *Relay) backoff(attempt int) time.Duration {
+	delay := r.config.InitialBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= r.config.MaxBackoff {
+			return r.config.MaxBackoff
+		}
+	}
+
+	return delay
+}
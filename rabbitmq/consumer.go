@@ -0,0 +1,148 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/delivery-much/dm-go/optel"
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ErrRejected is the error that marks a message as rejected by its handler, see Reject.
+var ErrRejected = errors.New("message rejected")
+
+// Reject wraps an error returned by a handler to mark the message as rejected.
+// Rejected messages are not retried: they are moved straight to the parking-lot queue of the retry topology,
+// or rejected without requeue, and dead-lettered if the queue has a dead-letter exchange.
+//
+// Ex.:
+//
+//	if order.ID == "" {
+//		return rabbitmq.Reject(errors.New("order without id"))
+//	}
+func Reject(err error) error {
+	return fmt.Errorf("%w: %w", ErrRejected, err)
+}
+
+const (
+	// defaultHandlerTimeout is the timeout of the handler context when the consumer does not configure one.
+	defaultHandlerTimeout = 10 * time.Second
+	// defaultPrefetch is the prefetch of the consumer when it does not configure one.
+	defaultPrefetch = 10
+)
+
+// consumer represents a consumer registered in the client, with its own channel.
+type consumer struct {
+	tag   string
+	queue string
+	ch    *amqp.Channel
+	// confirms receives the confirmations of the messages moved through the retry topology, nil if there is none
+	confirms   <-chan amqp.Confirmation
+	subHandler SubscribeHandler
+	// ctx and cg are the context and the config the consumer was subscribed with, to subscribe it again on reconnect
+	ctx   context.Context
+	cg    ConsumerConfig
+	retry *retryTopology
+	// baseCtx is the context the handler contexts are derived from
+	baseCtx context.Context
+	timeout time.Duration
+	// metricAttrs are the attributes of the metrics of the consumer
+	metricAttrs metric.MeasurementOption
+	// stateMu guards the status and the last delivery time, reported by the health check
+	stateMu      sync.Mutex
+	status       string
+	lastDelivery time.Time
+	// stopping is closed when the consumer is cancelled, so the deliveries still buffered by the client are not handled
+	stopping chan struct{}
+	stopOnce sync.Once
+	// done is closed when the consume loop returns, after the last in-flight message is settled
+	done chan struct{}
+}
+
+// stop stops handling the deliveries. The deliveries received after it are left unacknowledged,
+// so the broker requeues them when the channel is closed.
+func (cs *consumer) stop() {
+	cs.stopOnce.Do(func() { close(cs.stopping) })
+}
+
+// stopped returns if the consumer stopped handling the deliveries, see stop.
+func (cs *consumer) stopped() bool {
+	select {
+	case <-cs.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// consumeLoop handles the deliveries until the channel is closed, either by cancelling the consumer or closing the connection.
+// Once the consumer is stopped, the remaining deliveries are drained without being handled nor acknowledged.
+func (cs *consumer) consumeLoop(deliveries <-chan amqp.Delivery) {
+	defer close(cs.done)
+	defer func() {
+		cs.stateMu.Lock()
+		defer cs.stateMu.Unlock()
+		if cs.status == ConsumerActive {
+			cs.status = ConsumerStopped
+		}
+	}()
+
+	m := getMetrics()
+	for d := range deliveries {
+		if cs.stopped() {
+			continue
+		}
+
+		cs.delivered()
+		end := m.startHandling(cs.baseCtx, cs.metricAttrs, d.Redelivered)
+		err := cs.handle(d)
+		end()
+
+		cs.settle(d, err)
+		m.settled(cs.baseCtx, cs.metricAttrs, err)
+	}
+}
+
+// handle invokes the handler with a context carrying the trace context and the request id of the message,
+// that is cancelled as soon as the handler returns.
+func (cs *consumer) handle(d amqp.Delivery) error {
+	ctx, cancel := context.WithTimeout(extractContext(cs.baseCtx, d.Headers), cs.timeout)
+	defer cancel()
+
+	ctx, end := optel.StartTrackConsumer(ctx, spanName(cs.queue, "process"), deliveryAttributes(cs.queue, d)...)
+	defer end()
+
+	// Invoke the handlerFunc func we passed as parameter.
+	err := cs.subHandler(ctx, newMessage(d))
+	if err != nil {
+		recordSpanError(ctx, err)
+	}
+
+	return err
+}
+
+// settle acknowledges the delivery if the handler succeeded,
+// otherwise moves it through the retry topology, or rejects it if there is none.
+// Deliveries rejected by the handler, see Reject, skip the retries and go straight to the parking-lot queue.
+func (cs *consumer) settle(d amqp.Delivery, err error) {
+	if err == nil {
+		d.Ack(false)
+		return
+	}
+
+	if cs.retry == nil {
+		d.Nack(false, false)
+		return
+	}
+
+	if errors.Is(err, ErrRejected) {
+		cs.retry.park(cs.ch, cs.confirms, d)
+		return
+	}
+
+	cs.retry.retry(cs.ch, cs.confirms, d)
+}
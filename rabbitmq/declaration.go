@@ -0,0 +1,179 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// ExchangeOptions represents the options used to declare the exchange of a consumer.
+// The zero value declares a durable, non auto-delete and non internal exchange without arguments.
+type ExchangeOptions struct {
+	// Transient declares a non durable exchange, that does not survive broker restarts.
+	Transient  bool
+	AutoDelete bool
+	Internal   bool
+	Arguments  amqp.Table
+}
+
+// QueueOptions represents the options used to declare the queue of a consumer.
+// The zero value declares a durable, non auto-delete and non exclusive classic queue without arguments.
+//
+// Ex.:
+//
+//	QueueOptions{
+//		Type:                 "quorum",
+//		MaxLength:            10000,
+//		MessageTTL:           24 * time.Hour,
+//		MaxPriority:          10,
+//		SingleActiveConsumer: true,
+//	}
+type QueueOptions struct {
+	// Transient declares a non durable queue, that does not survive broker restarts.
+	Transient  bool
+	AutoDelete bool
+	Exclusive  bool
+	// Type sets the x-queue-type argument: classic, quorum or stream.
+	Type string
+	// MaxLength sets the x-max-length argument, the maximum number of ready messages in the queue.
+	MaxLength int
+	// MessageTTL sets the x-message-ttl argument, how long a message can stay in the queue.
+	// Messages published with a shorter Expiration expire first, see Expiration.
+	MessageTTL time.Duration
+	// MaxPriority sets the x-max-priority argument, declaring a priority queue
+	// that delivers the messages with higher Priority first, up to this priority (at most 255, 10 recommended).
+	// The priority of an existing queue cannot be changed.
+	MaxPriority uint8
+	// Lazy sets the x-queue-mode argument to lazy, keeping the messages on disk.
+	Lazy bool
+	// SingleActiveConsumer sets the x-single-active-consumer argument,
+	// so only one consumer of the queue receives messages at a time.
+	SingleActiveConsumer bool
+	// Arguments are any other arguments of the queue declaration.
+	// The arguments set by the fields above take precedence.
+	Arguments amqp.Table
+}
+
+// Binding represents a binding between the consumer queue and its exchange.
+//
+// For headers exchanges, the Arguments are used to match the message headers instead of the Key.
+// Ex.:
+//
+//	Binding{
+//		Arguments: amqp.Table{"x-match": "all", "event": "order.created"},
+//	}
+type Binding struct {
+	Key       string
+	Arguments amqp.Table
+}
+
+// arguments returns the arguments of the queue declaration.
+func (qo QueueOptions) arguments() amqp.Table {
+	if qo.Arguments == nil && qo.Type == "" && qo.MaxLength == 0 && qo.MessageTTL == 0 && qo.MaxPriority == 0 && !qo.Lazy && !qo.SingleActiveConsumer {
+		return nil
+	}
+
+	args := amqp.Table{}
+	for k, v := range qo.Arguments {
+		args[k] = v
+	}
+	if qo.Type != "" {
+		args["x-queue-type"] = qo.Type
+	}
+	if qo.MaxLength > 0 {
+		args["x-max-length"] = int64(qo.MaxLength)
+	}
+	if qo.MessageTTL > 0 {
+		args["x-message-ttl"] = qo.MessageTTL.Milliseconds()
+	}
+	if qo.MaxPriority > 0 {
+		args["x-max-priority"] = int64(qo.MaxPriority)
+	}
+	if qo.Lazy {
+		args["x-queue-mode"] = "lazy"
+	}
+	if qo.SingleActiveConsumer {
+		args["x-single-active-consumer"] = true
+	}
+
+	return args
+}
+
+// bindings returns every binding of the consumer queue.
+// The BindingKey is always bound when there are no other Bindings, as it is the default binding of the consumer.
+func (cg ConsumerConfig) bindings() []Binding {
+	if len(cg.Bindings) == 0 {
+		return []Binding{{Key: cg.BindingKey}}
+	}
+
+	if cg.BindingKey != "" {
+		return append([]Binding{{Key: cg.BindingKey}}, cg.Bindings...)
+	}
+
+	return cg.Bindings
+}
+
+// declareExchange declares the exchange of the consumer,
+// or just checks that it exists in passive mode.
+func declareExchange(ch *amqp.Channel, cg ConsumerConfig) error {
+	declare := ch.ExchangeDeclare
+	if cg.Passive {
+		declare = ch.ExchangeDeclarePassive
+	}
+
+	err := declare(
+		cg.ExchangeName,        // name of the exchange
+		cg.ExchangeType,        // type
+		!cg.Exchange.Transient, // durable
+		cg.Exchange.AutoDelete, // delete when complete
+		cg.Exchange.Internal,   // internal
+		false,                  // noWait
+		cg.Exchange.Arguments,  // arguments
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to register an Exchange: %s", err)
+	}
+
+	return nil
+}
+
+// declareQueue declares the queue of the consumer and binds it to the exchange,
+// or just checks that it exists in passive mode, where the bindings are expected to be provisioned as well.
+func declareQueue(ch *amqp.Channel, cg ConsumerConfig) (amqp.Queue, error) {
+	declare := ch.QueueDeclare
+	if cg.Passive {
+		declare = ch.QueueDeclarePassive
+	}
+
+	queue, err := declare(
+		cg.QueueName,         // name of the queue
+		!cg.Queue.Transient,  // durable
+		cg.Queue.AutoDelete,  // delete when usused
+		cg.Queue.Exclusive,   // exclusive
+		false,                // noWait
+		cg.Queue.arguments(), // arguments
+	)
+	if err != nil {
+		return queue, fmt.Errorf("Failed to register an Queue: %s", err)
+	}
+
+	if cg.Passive {
+		return queue, nil
+	}
+
+	for _, b := range cg.bindings() {
+		err = ch.QueueBind(
+			queue.Name,      // name of the queue
+			b.Key,           // bindingKey
+			cg.ExchangeName, // sourceExchange
+			false,           // noWait
+			b.Arguments,     // arguments
+		)
+		if err != nil {
+			return queue, fmt.Errorf("Queue Bind: %s", err)
+		}
+	}
+
+	return queue, nil
+}
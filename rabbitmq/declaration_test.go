@@ -0,0 +1,73 @@
+package rabbitmq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueueOptionsArguments(t *testing.T) {
+	t.Run("Should return nil arguments for the zero value", func(t *testing.T) {
+		assert.Nil(t, QueueOptions{}.arguments())
+	})
+	t.Run("Should map the options to the queue arguments", func(t *testing.T) {
+		qo := QueueOptions{
+			Type:                 "quorum",
+			MaxLength:            100,
+			MessageTTL:           time.Minute,
+			MaxPriority:          10,
+			Lazy:                 true,
+			SingleActiveConsumer: true,
+		}
+
+		assert.Equal(t, amqp.Table{
+			"x-queue-type":             "quorum",
+			"x-max-length":             int64(100),
+			"x-message-ttl":            int64(60000),
+			"x-max-priority":           int64(10),
+			"x-queue-mode":             "lazy",
+			"x-single-active-consumer": true,
+		}, qo.arguments())
+	})
+	t.Run("Should give precedence to the options over the raw arguments", func(t *testing.T) {
+		raw := amqp.Table{
+			"x-queue-type": "classic",
+			"x-overflow":   "reject-publish",
+		}
+		qo := QueueOptions{
+			Type:      "quorum",
+			Arguments: raw,
+		}
+
+		assert.Equal(t, amqp.Table{
+			"x-queue-type": "quorum",
+			"x-overflow":   "reject-publish",
+		}, qo.arguments())
+		assert.Equal(t, "classic", raw["x-queue-type"])
+	})
+}
+
+func TestConsumerConfigBindings(t *testing.T) {
+	t.Run("Should bind the binding key by default, even if empty", func(t *testing.T) {
+		assert.Equal(t, []Binding{{Key: ""}}, ConsumerConfig{}.bindings())
+		assert.Equal(t, []Binding{{Key: "order.*"}}, ConsumerConfig{BindingKey: "order.*"}.bindings())
+	})
+	t.Run("Should bind the binding key alongside the other bindings", func(t *testing.T) {
+		cg := ConsumerConfig{
+			BindingKey: "order.created",
+			Bindings:   []Binding{{Key: "order.updated"}},
+		}
+
+		assert.Equal(t, []Binding{{Key: "order.created"}, {Key: "order.updated"}}, cg.bindings())
+	})
+	t.Run("Should only use the bindings when the binding key is empty", func(t *testing.T) {
+		headers := Binding{Arguments: amqp.Table{"x-match": "all", "event": "order.created"}}
+		cg := ConsumerConfig{
+			Bindings: []Binding{headers},
+		}
+
+		assert.Equal(t, []Binding{headers}, cg.bindings())
+	})
+}
@@ -0,0 +1,104 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/delivery-much/dm-go/logger"
+)
+
+// defaultDeduplicationTTL is how long a handled message key is kept when the DeduplicationConfig does not configure it.
+const defaultDeduplicationTTL = 24 * time.Hour
+
+// DeduplicationStore represents the storage of the keys of the messages already handled by a consumer.
+//
+// The package provides an in-memory implementation, see NewMemoryDeduplicationStore,
+// and a MongoDB implementation, see NewMongoDeduplicationStore.
+type DeduplicationStore interface {
+	// Reserve atomically stores the key if it is not stored yet, expiring it after the ttl.
+	// It returns false if the key was already stored.
+	Reserve(ctx context.Context, key string, ttl time.Duration) (bool, error)
+
+	// Release removes the key from the store, so a message with the same key can be handled again.
+	Release(ctx context.Context, key string) error
+}
+
+// DeduplicationConfig represents the configuration of the Deduplicate middleware.
+type DeduplicationConfig struct {
+	Store DeduplicationStore
+	// TTL is how long the key of a handled message is kept in the store. Default: 24 hours.
+	TTL time.Duration
+	// Key returns the deduplication key of a message. Default: the message id.
+	// Messages with an empty key are always handled.
+	Key func(msg *Message) string
+}
+
+// Deduplicate returns a middleware that handles each message only once, keyed by its message id or by the configured key function.
+//
+// The key is reserved in the store before invoking the handler, and released if the handler fails or panics,
+// so the message can be retried. Messages whose key is already reserved are acknowledged without invoking the handler.
+// If the application crashes while handling a message, its key stays reserved until the TTL expires.
+//
+// Ex.:
+//
+//	err = client.Subscribe(ctx, rabbitmq.ConsumerConfig{
+//		...
+//		Middlewares: []rabbitmq.HandlerMiddleware{
+//			rabbitmq.Deduplicate(rabbitmq.DeduplicationConfig{
+//				Store: rabbitmq.NewMemoryDeduplicationStore(),
+//			}),
+//		},
+//	}, handler)
+func Deduplicate(cfg DeduplicationConfig) HandlerMiddleware {
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultDeduplicationTTL
+	}
+	if cfg.Key == nil {
+		cfg.Key = func(msg *Message) string {
+			return msg.Delivery.MessageId
+		}
+	}
+
+	return func(next SubscribeHandler) SubscribeHandler {
+		return func(ctx context.Context, msg *Message) (err error) {
+			key := cfg.Key(msg)
+			if key == "" {
+				return next(ctx, msg)
+			}
+
+			reserved, err := cfg.Store.Reserve(ctx, key, cfg.TTL)
+			if err != nil {
+				return fmt.Errorf("Failed to reserve the deduplication key %s: %w", key, err)
+			}
+			if !reserved {
+				logger.Infow(ctx, "Skipping duplicated message",
+					"deduplication_key", key,
+					"exchange", msg.Delivery.Exchange,
+					"routing_key", msg.Delivery.RoutingKey,
+				)
+				return nil
+			}
+
+			succeeded := false
+			defer func() {
+				if succeeded {
+					return
+				}
+
+				// the handler context may be already done, but the key must be released anyway
+				rErr := cfg.Store.Release(context.WithoutCancel(ctx), key)
+				if rErr != nil {
+					logger.Errorw(ctx, "Failed to release the deduplication key",
+						"deduplication_key", key,
+						"error", rErr.Error(),
+					)
+				}
+			}()
+
+			err = next(ctx, msg)
+			succeeded = err == nil
+			return err
+		}
+	}
+}
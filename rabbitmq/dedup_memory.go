@@ -0,0 +1,66 @@
+package rabbitmq
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryPurgeInterval is the minimum interval between the removals of the expired keys of a MemoryDeduplicationStore.
+const memoryPurgeInterval = time.Minute
+
+// MemoryDeduplicationStore represents a DeduplicationStore that keeps the keys in memory.
+// The keys are not shared between instances of the application, nor survive restarts.
+type MemoryDeduplicationStore struct {
+	mu        sync.Mutex
+	keys      map[string]time.Time
+	lastPurge time.Time
+	now       func() time.Time
+}
+
+// NewMemoryDeduplicationStore returns a new empty MemoryDeduplicationStore.
+func NewMemoryDeduplicationStore() *MemoryDeduplicationStore {
+	return &MemoryDeduplicationStore{
+		keys: map[string]time.Time{},
+		now:  time.Now,
+	}
+}
+
+// Reserve stores the key if it is not stored yet or if it has expired, returning false otherwise.
+func (s *MemoryDeduplicationStore) Reserve(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.purge(now)
+
+	if expiresAt, ok := s.keys[key]; ok && now.Before(expiresAt) {
+		return false, nil
+	}
+
+	s.keys[key] = now.Add(ttl)
+	return true, nil
+}
+
+// Release removes the key from the store.
+func (s *MemoryDeduplicationStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, key)
+	return nil
+}
+
+// purge removes the expired keys, at most once every memoryPurgeInterval.
+func (s *MemoryDeduplicationStore) purge(now time.Time) {
+	if now.Sub(s.lastPurge) < memoryPurgeInterval {
+		return
+	}
+
+	for key, expiresAt := range s.keys {
+		if !now.Before(expiresAt) {
+			delete(s.keys, key)
+		}
+	}
+	s.lastPurge = now
+}
@@ -0,0 +1,69 @@
+package rabbitmq
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoDeduplicationStore represents a DeduplicationStore that keeps the keys in a MongoDB collection,
+// shared between every instance of the application.
+//
+// Each key is stored as a document with the key as its _id and an expires_at field,
+// and a TTL index removes the expired documents.
+type MongoDeduplicationStore struct {
+	coll *mongo.Collection
+}
+
+// NewMongoDeduplicationStore returns a new MongoDeduplicationStore that uses the given collection,
+// creating the TTL index on its expires_at field.
+//
+// Ex.:
+//
+//	store, err := rabbitmq.NewMongoDeduplicationStore(ctx, db.Collection("processed_messages"))
+func NewMongoDeduplicationStore(ctx context.Context, coll *mongo.Collection) (*MongoDeduplicationStore, error) {
+	_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &MongoDeduplicationStore{coll: coll}, nil
+}
+
+// Reserve inserts the key in the collection, returning false if it already exists.
+// Since the TTL index does not remove the documents as soon as they expire,
+// an existing expired key is reserved again.
+func (s *MongoDeduplicationStore) Reserve(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	_, err := s.coll.InsertOne(ctx, bson.M{"_id": key, "expires_at": expiresAt})
+	if err == nil {
+		return true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return false, err
+	}
+
+	res, err := s.coll.UpdateOne(ctx,
+		bson.M{"_id": key, "expires_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"expires_at": expiresAt}},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return res.ModifiedCount == 1, nil
+}
+
+// Release deletes the key from the collection.
+func (s *MongoDeduplicationStore) Release(ctx context.Context, key string) error {
+	_, err := s.coll.DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
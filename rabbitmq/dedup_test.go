@@ -0,0 +1,105 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryDeduplicationStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should reserve a key only once until it is released", func(t *testing.T) {
+		s := NewMemoryDeduplicationStore()
+
+		reserved, err := s.Reserve(ctx, "key", time.Hour)
+		assert.Nil(t, err)
+		assert.True(t, reserved)
+
+		reserved, _ = s.Reserve(ctx, "key", time.Hour)
+		assert.False(t, reserved)
+
+		assert.Nil(t, s.Release(ctx, "key"))
+
+		reserved, _ = s.Reserve(ctx, "key", time.Hour)
+		assert.True(t, reserved)
+	})
+	t.Run("Should reserve an expired key again and purge the expired keys", func(t *testing.T) {
+		now := time.Now()
+		s := NewMemoryDeduplicationStore()
+		s.now = func() time.Time { return now }
+
+		s.Reserve(ctx, "key", time.Minute)
+		s.Reserve(ctx, "other-key", time.Minute)
+
+		now = now.Add(2 * time.Minute)
+		reserved, _ := s.Reserve(ctx, "key", time.Minute)
+
+		assert.True(t, reserved)
+		assert.NotContains(t, s.keys, "other-key")
+	})
+}
+
+func TestDeduplicate(t *testing.T) {
+	ctx := context.Background()
+	msg := &Message{Delivery: amqp.Delivery{MessageId: "message-id"}}
+
+	t.Run("Should handle a message only once", func(t *testing.T) {
+		calls := 0
+		h := Deduplicate(DeduplicationConfig{Store: NewMemoryDeduplicationStore()})(func(ctx context.Context, msg *Message) error {
+			calls++
+			return nil
+		})
+
+		assert.Nil(t, h(ctx, msg))
+		assert.Nil(t, h(ctx, msg))
+		assert.Equal(t, 1, calls)
+	})
+	t.Run("Should handle the message again after the handler fails", func(t *testing.T) {
+		calls := 0
+		errMock := errors.New("handler error")
+		h := Deduplicate(DeduplicationConfig{Store: NewMemoryDeduplicationStore()})(func(ctx context.Context, msg *Message) error {
+			calls++
+			if calls == 1 {
+				return errMock
+			}
+			return nil
+		})
+
+		assert.Equal(t, errMock, h(ctx, msg))
+		assert.Nil(t, h(ctx, msg))
+		assert.Equal(t, 2, calls)
+	})
+	t.Run("Should release the key when the handler panics", func(t *testing.T) {
+		store := NewMemoryDeduplicationStore()
+		h := Deduplicate(DeduplicationConfig{Store: store})(func(ctx context.Context, msg *Message) error {
+			panic("boom")
+		})
+
+		assert.Panics(t, func() { h(ctx, msg) })
+		assert.NotContains(t, store.keys, "message-id")
+	})
+	t.Run("Should use the configured key and always handle messages without key", func(t *testing.T) {
+		calls := 0
+		h := Deduplicate(DeduplicationConfig{
+			Store: NewMemoryDeduplicationStore(),
+			Key: func(msg *Message) string {
+				return string(msg.Body)
+			},
+		})(func(ctx context.Context, msg *Message) error {
+			calls++
+			return nil
+		})
+
+		h(ctx, &Message{Body: []byte("order-1")})
+		h(ctx, &Message{Body: []byte("order-1")})
+		h(ctx, &Message{})
+		h(ctx, &Message{})
+
+		assert.Equal(t, 3, calls)
+	})
+}
@@ -0,0 +1,744 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+// deliveryCountHeader is the header that counts the times a message was requeued, set by the Fake like quorum queues do.
+const deliveryCountHeader = "x-delivery-count"
+
+// Fake represents an in-memory broker that implements the RabbitMQ interface,
+// meant for unit tests of consumers and publishers without a running RabbitMQ.
+//
+// The published messages are routed to the queues of the subscribers following the semantics of the
+// direct, topic, fanout and headers exchanges, and the default exchange routes them to the queue named by the routing key.
+// Messages published in exchanges that were not declared, by a Subscribe or by DeclareExchange, are only recorded.
+// Priority queues, see QueueOptions.MaxPriority, deliver the messages with higher priority first,
+// but the messages do not expire.
+//
+// The handled messages are settled like the Client does: acknowledged when the handler succeeds,
+// retried after the delays of the RetryConfig, and dead-lettered when rejected or when the retries are exhausted, see DeadLetters.
+// The queues configured with RequeueOnError nack the failed messages with requeue instead, delivering them again, see Requeued.
+//
+// Ex.:
+//
+//	fake := rabbitmq.NewFake()
+//	fake.Subscribe(ctx, cg, handler)
+//
+//	fake.Publish(ctx, "orders", "order.created", amqp.Publishing{Body: body})
+//	err := fake.Wait(ctx) // waits for every message to be handled
+type Fake struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	// exchanges maps the name of the declared exchanges to their type
+	exchanges   map[string]string
+	bindings    map[string][]fakeBinding
+	queues      map[string]*fakeQueue
+	consumers   []*fakeConsumer
+	middlewares []HandlerMiddleware
+	// replies maps the reply-to of the pending calls to the channel that receives their reply
+	replies map[string]chan amqp.Delivery
+
+	published   []Published
+	deadLetters []DeadLetter
+	requeued    []Requeued
+	// requeues maps the queues configured with RequeueOnError to the number of times each message is requeued
+	requeues map[string]int
+
+	deliveryTag  uint64
+	inFlight     int
+	scheduled    int
+	shuttingDown bool
+	closed       bool
+}
+
+// Published represents a message published in the Fake.
+type Published struct {
+	Exchange   string
+	RoutingKey string
+	Publishing amqp.Publishing
+}
+
+// DeadLetter represents a message dead-lettered by a consumer of the Fake,
+// either rejected by the handler or after its retries were exhausted.
+type DeadLetter struct {
+	// Queue is the name of the queue of the consumer
+	Queue    string
+	Delivery amqp.Delivery
+	// Err is the error returned by the handler
+	Err error
+}
+
+// Requeued represents a message nacked with requeue by a consumer of the Fake, see RequeueOnError.
+type Requeued struct {
+	// Queue is the name of the queue of the consumer
+	Queue    string
+	Delivery amqp.Delivery
+	// Err is the error returned by the handler
+	Err error
+}
+
+type fakeBinding struct {
+	queue string
+	Binding
+}
+
+type fakeQueue struct {
+	messages    []amqp.Delivery
+	consumers   int
+	maxPriority uint8
+}
+
+// push enqueues the delivery, ahead of the deliveries with lower priority when the queue is a priority queue.
+func (q *fakeQueue) push(d amqp.Delivery) {
+	priority := min(d.Priority, q.maxPriority)
+
+	i := len(q.messages)
+	for i > 0 && min(q.messages[i-1].Priority, q.maxPriority) < priority {
+		i--
+	}
+	q.messages = append(q.messages[:i], append([]amqp.Delivery{d}, q.messages[i:]...)...)
+}
+
+type fakeConsumer struct {
+	tag     string
+	queue   string
+	handler SubscribeHandler
+	retry   *retryTopology
+	baseCtx context.Context
+	timeout time.Duration
+
+	status       string
+	lastDelivery time.Time
+	stopped      bool
+	done         chan struct{}
+}
+
+// NewFake returns a new empty Fake.
+func NewFake() *Fake {
+	f := &Fake{
+		exchanges: map[string]string{},
+		bindings:  map[string][]fakeBinding{},
+		queues:    map[string]*fakeQueue{},
+		replies:   map[string]chan amqp.Delivery{},
+		requeues:  map[string]int{},
+	}
+	f.cond = sync.NewCond(&f.mu)
+
+	return f
+}
+
+// DeclareExchange declares an exchange of the given type (direct, topic, fanout or headers),
+// so the messages published in it are routed to the queues bound to it.
+func (f *Fake) DeclareExchange(name, kind string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.exchanges[name] = kind
+}
+
+// Published returns every message published in the Fake, in order.
+func (f *Fake) Published() []Published {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Published{}, f.published...)
+}
+
+// DeadLetters returns every message dead-lettered by the consumers of the Fake, in order.
+func (f *Fake) DeadLetters() []DeadLetter {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]DeadLetter{}, f.deadLetters...)
+}
+
+// Requeued returns every message nacked with requeue by the consumers of the Fake, in order.
+func (f *Fake) Requeued() []Requeued {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Requeued{}, f.requeued...)
+}
+
+// RequeueOnError makes the consumers of the queue nack with requeue the messages whose handler fails,
+// instead of retrying or dead-lettering them, so they are delivered again right away with the Redelivered flag,
+// like a consumer that requeues its failed messages.
+// Each message is requeued up to the given number of times, counted in its x-delivery-count header like quorum queues do,
+// then settled as usual, so a handler that always fails does not block Wait.
+//
+// Ex.:
+//
+//	fake.RequeueOnError("my-service.orders", 1)
+func (f *Fake) RequeueOnError(queue string, times int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requeues[queue] = times
+}
+
+// Wait waits until every routed message is handled, including the scheduled retries,
+// or until the context is done, returning its error.
+// Messages in queues without consumers are not waited for.
+func (f *Fake) Wait(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.cond.Broadcast()
+	})
+	defer stop()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for f.busy() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		f.cond.Wait()
+	}
+
+	return nil
+}
+
+// busy returns if there are messages being handled or waiting to be handled.
+// It must be called holding the lock.
+func (f *Fake) busy() bool {
+	if f.inFlight > 0 || f.scheduled > 0 {
+		return true
+	}
+
+	for _, q := range f.queues {
+		if q.consumers > 0 && len(q.messages) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Close closes the Fake, stopping every consumer without waiting for the in-flight messages.
+func (f *Fake) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	for _, fc := range f.consumers {
+		f.stop(fc)
+	}
+	for replyTo, r := range f.replies {
+		delete(f.replies, replyTo)
+		close(r)
+	}
+}
+
+// Shutdown stops every consumer, waiting for the in-flight messages to be handled until the context is done, and closes the Fake.
+func (f *Fake) Shutdown(ctx context.Context) error {
+	f.mu.Lock()
+	f.shuttingDown = true
+	consumers := f.consumers
+	for _, fc := range consumers {
+		f.stop(fc)
+	}
+	f.mu.Unlock()
+
+	defer f.Close()
+
+	for _, fc := range consumers {
+		select {
+		case <-fc.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// Ping returns amqp.ErrClosed if the Fake is closed.
+func (f *Fake) Ping() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return amqp.ErrClosed
+	}
+	return nil
+}
+
+// Use appends middlewares to the chain that wraps the handlers of the consumers subscribed after the call.
+func (f *Fake) Use(middlewares ...HandlerMiddleware) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.middlewares = append(f.middlewares, middlewares...)
+}
+
+// Health returns the health report of the Fake, that is down only when it is closed or a consumer is not active.
+func (f *Fake) Health(ctx context.Context) HealthReport {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	report := HealthReport{
+		Status:     HealthUp,
+		Connection: ComponentHealth{OK: !f.closed},
+		Channel:    ComponentHealth{OK: !f.closed},
+		Consumers:  []ConsumerHealth{},
+	}
+	if f.closed {
+		report.Status = HealthDown
+		report.Connection.Error = amqp.ErrClosed.Error()
+		report.Channel.Error = amqp.ErrClosed.Error()
+	}
+
+	for _, fc := range f.consumers {
+		h := ConsumerHealth{
+			Name:   fc.tag,
+			Queue:  fc.queue,
+			Status: fc.status,
+		}
+		if !fc.lastDelivery.IsZero() {
+			lastDelivery := fc.lastDelivery
+			h.LastDeliveryAt = &lastDelivery
+		}
+		if h.Status != ConsumerActive {
+			report.Status = HealthDown
+		}
+		report.Consumers = append(report.Consumers, h)
+	}
+
+	return report
+}
+
+// Subscribe declares the exchange, the queue and the bindings of the consumer in the Fake, or checks that they exist in passive mode,
+// and starts consuming the queue with the handler wrapped by the middlewares.
+func (f *Fake) Subscribe(ctx context.Context, cg ConsumerConfig, subHandler SubscribeHandler) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return amqp.ErrClosed
+	}
+	if f.shuttingDown {
+		return ErrShuttingDown
+	}
+
+	queueName := cg.QueueName
+	if queueName == "" {
+		queueName = "amq.gen-" + uuid.New().String()
+	}
+
+	err := f.declare(cg, queueName)
+	if err != nil {
+		return err
+	}
+
+	tag := cg.ConsumerName
+	if tag == "" {
+		tag = uuid.New().String()
+	}
+
+	timeout := cg.HandlerTimeout
+	if timeout <= 0 {
+		timeout = defaultHandlerTimeout
+	}
+
+	fc := &fakeConsumer{
+		tag:     tag,
+		queue:   queueName,
+		handler: chain(chain(subHandler, cg.Middlewares...), f.middlewares...),
+		baseCtx: context.WithoutCancel(ctx),
+		timeout: timeout,
+		status:  ConsumerActive,
+		done:    make(chan struct{}),
+	}
+	if cg.Retry != nil {
+		fc.retry = newRetryTopology(queueName, *cg.Retry)
+	}
+
+	f.consumers = append(f.consumers, fc)
+	f.queues[queueName].consumers++
+
+	go f.consume(fc)
+	context.AfterFunc(ctx, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.stop(fc)
+	})
+
+	return nil
+}
+
+// declare declares the exchange, the queue and the bindings of the consumer.
+// It must be called holding the lock.
+func (f *Fake) declare(cg ConsumerConfig, queueName string) error {
+	kind, exists := f.exchanges[cg.ExchangeName]
+
+	if cg.Passive {
+		if !exists {
+			return fmt.Errorf("Failed to register an Exchange: exchange %s not found", cg.ExchangeName)
+		}
+		if _, ok := f.queues[queueName]; !ok {
+			return fmt.Errorf("Failed to register an Queue: queue %s not found", queueName)
+		}
+		return nil
+	}
+
+	if exists && kind != cg.ExchangeType {
+		return fmt.Errorf("Failed to register an Exchange: exchange %s already declared as %s", cg.ExchangeName, kind)
+	}
+	f.exchanges[cg.ExchangeName] = cg.ExchangeType
+
+	if _, ok := f.queues[queueName]; !ok {
+		f.queues[queueName] = &fakeQueue{maxPriority: cg.Queue.MaxPriority}
+	}
+
+	for _, b := range cg.bindings() {
+		f.bindings[cg.ExchangeName] = append(f.bindings[cg.ExchangeName], fakeBinding{
+			queue:   queueName,
+			Binding: b,
+		})
+	}
+
+	return nil
+}
+
+// stop stops the consumer after its in-flight message is handled.
+// It must be called holding the lock.
+func (f *Fake) stop(fc *fakeConsumer) {
+	if fc.stopped {
+		return
+	}
+
+	fc.stopped = true
+	if fc.status == ConsumerActive {
+		fc.status = ConsumerStopped
+	}
+	f.queues[fc.queue].consumers--
+	f.cond.Broadcast()
+}
+
+// Publish records the message and routes it to the queues bound to the exchange.
+func (f *Fake) Publish(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return fmt.Errorf("Failed to publish a message: %s", amqp.ErrClosed)
+	}
+
+	msg.Headers = injectContext(ctx, msg.Headers)
+	f.published = append(f.published, Published{
+		Exchange:   exchange,
+		RoutingKey: routingKey,
+		Publishing: msg,
+	})
+
+	if r, ok := f.replies[routingKey]; ok && exchange == "" {
+		delete(f.replies, routingKey)
+		f.deliveryTag++
+		r <- delivery(f.deliveryTag, exchange, routingKey, msg)
+		return nil
+	}
+
+	for _, queue := range f.route(exchange, routingKey, msg.Headers) {
+		f.deliveryTag++
+		f.queues[queue].push(delivery(f.deliveryTag, exchange, routingKey, msg))
+	}
+	f.cond.Broadcast()
+
+	return nil
+}
+
+// PublishWithConfirm publishes the message like Publish, since the Fake confirms every message it accepts.
+func (f *Fake) PublishWithConfirm(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) error {
+	return f.Publish(ctx, exchange, routingKey, msg)
+}
+
+// Call publishes the request like Publish and waits for its reply, published by an RPCServer subscribed in the Fake,
+// following the same rules of the Client.
+func (f *Fake) Call(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) (*Message, error) {
+	msg = request(ctx, msg)
+	// like the broker does, the direct reply-to is replaced by a reply-to unique to the caller
+	msg.ReplyTo = directReplyTo + "." + uuid.New().String()
+
+	replies := make(chan amqp.Delivery, 1)
+	f.mu.Lock()
+	f.replies[msg.ReplyTo] = replies
+	f.mu.Unlock()
+
+	defer func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.replies, msg.ReplyTo)
+	}()
+
+	err := f.Publish(ctx, exchange, routingKey, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case d, ok := <-replies:
+		if !ok {
+			return nil, fmt.Errorf("Failed to receive the reply: %s", amqp.ErrClosed)
+		}
+		return reply(d)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// route returns the queues that a message published in the exchange should be routed to.
+// It must be called holding the lock.
+func (f *Fake) route(exchange, routingKey string, headers amqp.Table) (queues []string) {
+	if exchange == "" {
+		if _, ok := f.queues[routingKey]; ok {
+			queues = append(queues, routingKey)
+		}
+		return
+	}
+
+	kind := f.exchanges[exchange]
+	routed := map[string]bool{}
+	for _, b := range f.bindings[exchange] {
+		if routed[b.queue] || !bindingMatches(kind, b.Binding, routingKey, headers) {
+			continue
+		}
+
+		routed[b.queue] = true
+		queues = append(queues, b.queue)
+	}
+
+	return
+}
+
+// consume handles the messages of the consumer queue until the consumer is stopped.
+func (f *Fake) consume(fc *fakeConsumer) {
+	defer close(fc.done)
+
+	for {
+		f.mu.Lock()
+		q := f.queues[fc.queue]
+		for len(q.messages) == 0 && !fc.stopped {
+			f.cond.Wait()
+		}
+		if fc.stopped {
+			f.mu.Unlock()
+			return
+		}
+
+		d := q.messages[0]
+		q.messages = q.messages[1:]
+		d.ConsumerTag = fc.tag
+		fc.lastDelivery = time.Now()
+		f.inFlight++
+		f.mu.Unlock()
+
+		err := fc.handle(d)
+
+		f.mu.Lock()
+		f.settle(fc, d, err)
+		f.inFlight--
+		f.cond.Broadcast()
+		f.mu.Unlock()
+	}
+}
+
+// handle invokes the handler like the Client does, with a context carrying the trace context and the request id of the message.
+func (fc *fakeConsumer) handle(d amqp.Delivery) error {
+	ctx, cancel := context.WithTimeout(extractContext(fc.baseCtx, d.Headers), fc.timeout)
+	defer cancel()
+
+	return fc.handler(ctx, newMessage(d))
+}
+
+// settle acknowledges, retries or dead-letters the handled message, following the same rules of the Client,
+// or requeues it when its queue is configured with RequeueOnError.
+// It must be called holding the lock.
+func (f *Fake) settle(fc *fakeConsumer, d amqp.Delivery, err error) {
+	if err == nil {
+		return
+	}
+
+	if deliveryCount(d) < int64(f.requeues[fc.queue]) {
+		f.requeued = append(f.requeued, Requeued{Queue: fc.queue, Delivery: d, Err: err})
+
+		f.deliveryTag++
+		requeued := withDeliveryCount(d)
+		requeued.DeliveryTag = f.deliveryTag
+		requeued.Redelivered = true
+		// the broker puts the requeued message back in its position, ahead of the messages published after it
+		q := f.queues[fc.queue]
+		q.messages = append([]amqp.Delivery{requeued}, q.messages...)
+		return
+	}
+
+	if fc.retry == nil || errors.Is(err, ErrRejected) {
+		f.deadLetters = append(f.deadLetters, DeadLetter{Queue: fc.queue, Delivery: d, Err: err})
+		return
+	}
+
+	destination := fc.retry.destination(d)
+	if destination == fc.retry.parkingLot {
+		f.deadLetters = append(f.deadLetters, DeadLetter{Queue: fc.queue, Delivery: d, Err: err})
+		return
+	}
+
+	// simulates the message expiring in the retry queue and being dead-lettered back to the consumer queue
+	f.scheduled++
+	time.AfterFunc(fc.retry.delays[fc.retry.attempts(d)], func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		f.scheduled--
+		f.deliveryTag++
+		retried := withDeath(d, destination)
+		retried.DeliveryTag = f.deliveryTag
+		retried.Exchange = ""
+		retried.RoutingKey = fc.queue
+		retried.Redelivered = false
+		f.queues[fc.queue].push(retried)
+		f.cond.Broadcast()
+	})
+}
+
+// delivery returns the delivery of a published message.
+func delivery(tag uint64, exchange, routingKey string, msg amqp.Publishing) amqp.Delivery {
+	return amqp.Delivery{
+		Headers:         msg.Headers,
+		ContentType:     msg.ContentType,
+		ContentEncoding: msg.ContentEncoding,
+		DeliveryMode:    msg.DeliveryMode,
+		Priority:        msg.Priority,
+		CorrelationId:   msg.CorrelationId,
+		ReplyTo:         msg.ReplyTo,
+		Expiration:      msg.Expiration,
+		MessageId:       msg.MessageId,
+		Timestamp:       msg.Timestamp,
+		Type:            msg.Type,
+		UserId:          msg.UserId,
+		AppId:           msg.AppId,
+		DeliveryTag:     tag,
+		Exchange:        exchange,
+		RoutingKey:      routingKey,
+		Body:            msg.Body,
+	}
+}
+
+// withDeath returns a copy of the delivery with the x-death entry of the queue incremented, like the broker does when dead-lettering it.
+func withDeath(d amqp.Delivery, queue string) amqp.Delivery {
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+
+	deaths, _ := headers[xDeathHeader].([]interface{})
+	updated := []interface{}{amqp.Table{
+		"queue":        queue,
+		"reason":       "expired",
+		"count":        int64(1),
+		"exchange":     "",
+		"routing-keys": []interface{}{queue},
+	}}
+	for _, death := range deaths {
+		table, ok := death.(amqp.Table)
+		if ok && table["queue"] == queue {
+			count, _ := table["count"].(int64)
+			updated[0].(amqp.Table)["count"] = count + 1
+			continue
+		}
+		updated = append(updated, death)
+	}
+
+	headers[xDeathHeader] = updated
+	d.Headers = headers
+	return d
+}
+
+// deliveryCount returns the number of times the delivery was requeued, from its x-delivery-count header.
+func deliveryCount(d amqp.Delivery) int64 {
+	count, _ := d.Headers[deliveryCountHeader].(int64)
+	return count
+}
+
+// withDeliveryCount returns a copy of the delivery with the x-delivery-count header incremented, like quorum queues do when requeuing it.
+func withDeliveryCount(d amqp.Delivery) amqp.Delivery {
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+
+	headers[deliveryCountHeader] = deliveryCount(d) + 1
+	d.Headers = headers
+	return d
+}
+
+// bindingMatches returns if a message with the routing key and headers matches the binding of an exchange of the given type.
+func bindingMatches(kind string, b Binding, routingKey string, headers amqp.Table) bool {
+	switch kind {
+	case amqp.ExchangeFanout:
+		return true
+	case amqp.ExchangeTopic:
+		return topicMatches(strings.Split(b.Key, "."), strings.Split(routingKey, "."))
+	case amqp.ExchangeHeaders:
+		return headersMatch(b.Arguments, headers)
+	default:
+		return b.Key == routingKey
+	}
+}
+
+// topicMatches returns if the words of a routing key match the words of a topic binding key,
+// where "*" matches exactly one word and "#" matches zero or more words.
+func topicMatches(pattern, words []string) bool {
+	if len(pattern) == 0 {
+		return len(words) == 0
+	}
+
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(words); i++ {
+			if topicMatches(pattern[1:], words[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(words) > 0 && topicMatches(pattern[1:], words[1:])
+	default:
+		return len(words) > 0 && pattern[0] == words[0] && topicMatches(pattern[1:], words[1:])
+	}
+}
+
+// headersMatch returns if the message headers match the arguments of a headers binding,
+// all of them by default or any of them when x-match is "any".
+// Arguments starting with "x-" are not matched.
+func headersMatch(args, headers amqp.Table) bool {
+	matchAny := args["x-match"] == "any"
+
+	for k, v := range args {
+		if strings.HasPrefix(k, "x-") {
+			continue
+		}
+
+		matched := reflect.DeepEqual(headers[k], v)
+		if matchAny && matched {
+			return true
+		}
+		if !matchAny && !matched {
+			return false
+		}
+	}
+
+	return !matchAny
+}
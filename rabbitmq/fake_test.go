@@ -0,0 +1,280 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/delivery-much/dm-go/middleware"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ RabbitMQ = (*Fake)(nil)
+
+// recorder records the bodies of the messages handled by a fake consumer
+type recorder struct {
+	mu     sync.Mutex
+	bodies []string
+}
+
+func (r *recorder) handler(err error) SubscribeHandler {
+	return func(ctx context.Context, msg *Message) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.bodies = append(r.bodies, string(msg.Body))
+		return err
+	}
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.bodies...)
+}
+
+func waitFake(t *testing.T, f *Fake) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Nil(t, f.Wait(ctx))
+}
+
+func TestFakeRouting(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should route messages following the topic exchange semantics", func(t *testing.T) {
+		f := NewFake()
+		created, all := &recorder{}, &recorder{}
+		f.Subscribe(ctx, ConsumerConfig{ExchangeName: "orders", ExchangeType: "topic", QueueName: "created", BindingKey: "order.*.created"}, created.handler(nil))
+		f.Subscribe(ctx, ConsumerConfig{ExchangeName: "orders", ExchangeType: "topic", QueueName: "all", BindingKey: "order.#"}, all.handler(nil))
+
+		f.Publish(ctx, "orders", "order.br.created", amqp.Publishing{Body: []byte("1")})
+		f.Publish(ctx, "orders", "order.br.cancelled", amqp.Publishing{Body: []byte("2")})
+		f.Publish(ctx, "orders", "order", amqp.Publishing{Body: []byte("3")})
+		f.Publish(ctx, "payments", "order.br.created", amqp.Publishing{Body: []byte("4")})
+		waitFake(t, f)
+
+		assert.Equal(t, []string{"1"}, created.get())
+		assert.Equal(t, []string{"1", "2", "3"}, all.get())
+		assert.Len(t, f.Published(), 4)
+	})
+	t.Run("Should route messages following the direct, fanout and default exchange semantics", func(t *testing.T) {
+		f := NewFake()
+		direct, fanout := &recorder{}, &recorder{}
+		f.Subscribe(ctx, ConsumerConfig{ExchangeName: "direct", ExchangeType: "direct", QueueName: "direct", BindingKey: "key"}, direct.handler(nil))
+		f.Subscribe(ctx, ConsumerConfig{ExchangeName: "fanout", ExchangeType: "fanout", QueueName: "fanout"}, fanout.handler(nil))
+
+		f.Publish(ctx, "direct", "key", amqp.Publishing{Body: []byte("1")})
+		f.Publish(ctx, "direct", "other-key", amqp.Publishing{Body: []byte("2")})
+		f.Publish(ctx, "fanout", "any-key", amqp.Publishing{Body: []byte("3")})
+		f.Publish(ctx, "", "direct", amqp.Publishing{Body: []byte("4")})
+		waitFake(t, f)
+
+		assert.Equal(t, []string{"1", "4"}, direct.get())
+		assert.Equal(t, []string{"3"}, fanout.get())
+	})
+	t.Run("Should route messages following the headers exchange semantics", func(t *testing.T) {
+		f := NewFake()
+		all, any := &recorder{}, &recorder{}
+		f.Subscribe(ctx, ConsumerConfig{ExchangeName: "events", ExchangeType: "headers", QueueName: "all", Bindings: []Binding{
+			{Arguments: amqp.Table{"x-match": "all", "event": "created", "country": "br"}},
+		}}, all.handler(nil))
+		f.Subscribe(ctx, ConsumerConfig{ExchangeName: "events", ExchangeType: "headers", QueueName: "any", Bindings: []Binding{
+			{Arguments: amqp.Table{"x-match": "any", "event": "created", "country": "br"}},
+		}}, any.handler(nil))
+
+		f.Publish(ctx, "events", "", amqp.Publishing{Headers: amqp.Table{"event": "created", "country": "br"}, Body: []byte("1")})
+		f.Publish(ctx, "events", "", amqp.Publishing{Headers: amqp.Table{"event": "created", "country": "ar"}, Body: []byte("2")})
+		waitFake(t, f)
+
+		assert.Equal(t, []string{"1"}, all.get())
+		assert.Equal(t, []string{"1", "2"}, any.get())
+	})
+	t.Run("Should propagate the request id from the publisher to the handler", func(t *testing.T) {
+		f := NewFake()
+		var reqID string
+		f.Subscribe(ctx, ConsumerConfig{ExchangeName: "orders", ExchangeType: "direct", QueueName: "orders", BindingKey: "key"}, func(ctx context.Context, msg *Message) error {
+			reqID = middleware.GetReqID(ctx)
+			return nil
+		})
+
+		f.Publish(context.WithValue(ctx, middleware.RequestIDKey, "req-id"), "orders", "key", amqp.Publishing{})
+		waitFake(t, f)
+
+		assert.Equal(t, "req-id", reqID)
+	})
+	t.Run("Should wrap the handlers with the middlewares of the client", func(t *testing.T) {
+		f := NewFake()
+		var calls []string
+		f.Use(func(next SubscribeHandler) SubscribeHandler {
+			return func(ctx context.Context, msg *Message) error {
+				calls = append(calls, "middleware")
+				return next(ctx, msg)
+			}
+		})
+		f.Subscribe(ctx, ConsumerConfig{ExchangeName: "orders", ExchangeType: "direct", QueueName: "orders", BindingKey: "key"}, func(ctx context.Context, msg *Message) error {
+			calls = append(calls, "handler")
+			return nil
+		})
+
+		f.Publish(ctx, "orders", "key", amqp.Publishing{})
+		waitFake(t, f)
+
+		assert.Equal(t, []string{"middleware", "handler"}, calls)
+	})
+	t.Run("Should deliver the messages with higher priority first in priority queues", func(t *testing.T) {
+		f := NewFake()
+		r := &recorder{}
+		cg := ConsumerConfig{ExchangeName: "notifications", ExchangeType: "direct", QueueName: "notifications", BindingKey: "push", Queue: QueueOptions{MaxPriority: 5}}
+		// subscribed with a cancelled context, so the messages stay in the queue until the second consumer
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		f.Subscribe(cancelled, cg, r.handler(nil))
+		assert.Eventually(t, func() bool {
+			return f.Health(ctx).Consumers[0].Status == ConsumerStopped
+		}, time.Second, time.Millisecond)
+
+		f.Publish(ctx, "notifications", "push", amqp.Publishing{Body: []byte("low")})
+		f.Publish(ctx, "notifications", "push", amqp.Publishing{Priority: 9, Body: []byte("urgent")})
+		f.Publish(ctx, "notifications", "push", amqp.Publishing{Priority: 5, Body: []byte("capped")})
+		f.Publish(ctx, "notifications", "push", amqp.Publishing{Priority: 1, Body: []byte("normal")})
+		f.Subscribe(ctx, cg, r.handler(nil))
+		waitFake(t, f)
+
+		assert.Equal(t, []string{"urgent", "capped", "normal", "low"}, r.get())
+	})
+	t.Run("Should fail a passive subscribe when the topology does not exist", func(t *testing.T) {
+		f := NewFake()
+
+		err := f.Subscribe(ctx, ConsumerConfig{ExchangeName: "orders", ExchangeType: "topic", QueueName: "orders", Passive: true}, (&recorder{}).handler(nil))
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestFakeSettlement(t *testing.T) {
+	ctx := context.Background()
+	cg := ConsumerConfig{ExchangeName: "orders", ExchangeType: "direct", QueueName: "orders", BindingKey: "key"}
+	errMock := errors.New("handler error")
+
+	t.Run("Should dead-letter a failed message without retry topology", func(t *testing.T) {
+		f := NewFake()
+		r := &recorder{}
+		f.Subscribe(ctx, cg, r.handler(errMock))
+
+		f.Publish(ctx, "orders", "key", amqp.Publishing{Body: []byte("1")})
+		waitFake(t, f)
+
+		deadLetters := f.DeadLetters()
+		assert.Equal(t, []string{"1"}, r.get())
+		assert.Len(t, deadLetters, 1)
+		assert.Equal(t, "orders", deadLetters[0].Queue)
+		assert.Equal(t, errMock, deadLetters[0].Err)
+	})
+	t.Run("Should retry a failed message after the delays and then dead-letter it", func(t *testing.T) {
+		f := NewFake()
+		r := &recorder{}
+		retryCG := cg
+		retryCG.Retry = &RetryConfig{Delays: []time.Duration{time.Millisecond, 2 * time.Millisecond}}
+		f.Subscribe(ctx, retryCG, r.handler(errMock))
+
+		f.Publish(ctx, "orders", "key", amqp.Publishing{Body: []byte("1")})
+		waitFake(t, f)
+
+		deadLetters := f.DeadLetters()
+		assert.Equal(t, []string{"1", "1", "1"}, r.get())
+		assert.Len(t, deadLetters, 1)
+		assert.Equal(t, 2, newRetryTopology("orders", *retryCG.Retry).attempts(deadLetters[0].Delivery))
+	})
+	t.Run("Should dead-letter a rejected message without retrying it", func(t *testing.T) {
+		f := NewFake()
+		r := &recorder{}
+		retryCG := cg
+		retryCG.Retry = &RetryConfig{Delays: []time.Duration{time.Millisecond}}
+		f.Subscribe(ctx, retryCG, r.handler(Reject(errMock)))
+
+		f.Publish(ctx, "orders", "key", amqp.Publishing{Body: []byte("1")})
+		waitFake(t, f)
+
+		assert.Equal(t, []string{"1"}, r.get())
+		assert.Len(t, f.DeadLetters(), 1)
+	})
+	t.Run("Should deliver a requeued message again", func(t *testing.T) {
+		f := NewFake()
+		f.RequeueOnError("orders", 1)
+		calls := 0
+		redelivered := false
+		f.Subscribe(ctx, cg, func(ctx context.Context, msg *Message) error {
+			calls++
+			if calls == 1 {
+				return errMock
+			}
+			redelivered = msg.Delivery.Redelivered
+			return nil
+		})
+
+		f.Publish(ctx, "orders", "key", amqp.Publishing{Body: []byte("1")})
+		waitFake(t, f)
+
+		assert.Equal(t, 2, calls)
+		assert.True(t, redelivered)
+		assert.Empty(t, f.DeadLetters())
+
+		requeued := f.Requeued()
+		assert.Len(t, requeued, 1)
+		assert.Equal(t, "orders", requeued[0].Queue)
+		assert.Equal(t, errMock, requeued[0].Err)
+		assert.False(t, requeued[0].Delivery.Redelivered)
+	})
+	t.Run("Should settle the message as usual after it is requeued the configured times", func(t *testing.T) {
+		f := NewFake()
+		f.RequeueOnError("orders", 2)
+		r := &recorder{}
+		f.Subscribe(ctx, cg, r.handler(errMock))
+
+		f.Publish(ctx, "orders", "key", amqp.Publishing{Body: []byte("1")})
+		waitFake(t, f)
+
+		assert.Equal(t, []string{"1", "1", "1"}, r.get())
+		assert.Len(t, f.Requeued(), 2)
+		deadLetters := f.DeadLetters()
+		assert.Len(t, deadLetters, 1)
+		assert.Equal(t, int64(2), deadLetters[0].Delivery.Headers["x-delivery-count"])
+	})
+	t.Run("Should stop consuming after shutdown", func(t *testing.T) {
+		f := NewFake()
+		r := &recorder{}
+		f.Subscribe(ctx, cg, r.handler(nil))
+
+		assert.Nil(t, f.Shutdown(ctx))
+
+		assert.NotNil(t, f.Publish(ctx, "orders", "key", amqp.Publishing{Body: []byte("1")}))
+		assert.Equal(t, amqp.ErrClosed, f.Ping())
+		assert.Equal(t, HealthDown, f.Health(ctx).Status)
+		assert.Empty(t, r.get())
+	})
+}
+
+func TestTopicMatches(t *testing.T) {
+	cases := []struct {
+		pattern string
+		key     string
+		matches bool
+	}{
+		{"order.created", "order.created", true},
+		{"order.*", "order.created", true},
+		{"order.*", "order.br.created", false},
+		{"order.#", "order", true},
+		{"order.#.created", "order.br.sp.created", true},
+		{"#", "any.key", true},
+		{"*.created", "created", false},
+	}
+
+	for _, c := range cases {
+		t.Run("Should match "+c.pattern+" against "+c.key, func(t *testing.T) {
+			assert.Equal(t, c.matches, bindingMatches(amqp.ExchangeTopic, Binding{Key: c.pattern}, c.key, nil))
+		})
+	}
+}
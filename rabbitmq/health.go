@@ -0,0 +1,175 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/delivery-much/dm-go/render"
+)
+
+const (
+	// HealthUp is the status of a healthy client.
+	HealthUp = "up"
+	// HealthDown is the status of a client with a closed connection, a failed channel probe or a consumer that is not active.
+	HealthDown = "down"
+
+	// ConsumerActive is the status of a consumer receiving messages.
+	ConsumerActive = "active"
+	// ConsumerCancelled is the status of a consumer cancelled by the broker, e.g. when its queue is deleted.
+	ConsumerCancelled = "cancelled"
+	// ConsumerStopped is the status of a consumer stopped by the application or by the closing of its channel.
+	ConsumerStopped = "stopped"
+
+	// healthProbeTimeout is the timeout of the channel probe of the HealthHandler.
+	healthProbeTimeout = 5 * time.Second
+)
+
+// HealthReport represents the health of a client.
+type HealthReport struct {
+	Status     string           `json:"status"`
+	Connection ComponentHealth  `json:"connection"`
+	Channel    ComponentHealth  `json:"channel"`
+	Consumers  []ConsumerHealth `json:"consumers"`
+}
+
+// ComponentHealth represents the health of the connection or of the channel probe.
+type ComponentHealth struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// ConsumerHealth represents the health of a consumer.
+type ConsumerHealth struct {
+	Name           string     `json:"name"`
+	Queue          string     `json:"queue"`
+	Status         string     `json:"status"`
+	LastDeliveryAt *time.Time `json:"last_delivery_at,omitempty"`
+}
+
+// Health returns the health report of the client: the state of the connection,
+// the result of opening and closing a channel, and the status of each consumer.
+// The channel probe fails if the context is done before it finishes.
+func (c *Client) Health(ctx context.Context) HealthReport {
+	report := HealthReport{
+		Status:    HealthUp,
+		Consumers: []ConsumerHealth{},
+	}
+
+	err := c.Ping()
+	if err != nil {
+		report.Connection.Error = err.Error()
+		report.Channel.Error = err.Error()
+	} else {
+		report.Connection.OK = true
+
+		err = c.probeChannel(ctx)
+		if err != nil {
+			report.Channel.Error = err.Error()
+		} else {
+			report.Channel.OK = true
+		}
+	}
+
+	c.mu.Lock()
+	consumers := slices.Clone(c.consumers)
+	c.mu.Unlock()
+
+	for _, cs := range consumers {
+		ch := cs.health()
+		report.Consumers = append(report.Consumers, ch)
+		if ch.Status != ConsumerActive {
+			report.Status = HealthDown
+		}
+	}
+
+	if !report.Connection.OK || !report.Channel.OK {
+		report.Status = HealthDown
+	}
+
+	return report
+}
+
+// probeChannel opens and closes a channel, checking that the broker is responsive.
+func (c *Client) probeChannel(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		ch, err := openChannel(c.connection())
+		if err != nil {
+			done <- err
+			return
+		}
+		done <- ch.Close()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("Failed to probe a channel: %s", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("Failed to probe a channel: %s", ctx.Err())
+	}
+}
+
+// HealthHandler returns an http.Handler that serves the health report of the client as JSON,
+// with the status 200 when the client is up and 503 when it is down.
+//
+// Ex.:
+//
+//	r := chi.NewRouter()
+//	r.Get("/health/rabbitmq", rabbitmq.HealthHandler(client).ServeHTTP)
+func HealthHandler(client RabbitMQ) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), healthProbeTimeout)
+		defer cancel()
+
+		report := client.Health(ctx)
+
+		status := http.StatusOK
+		if report.Status != HealthUp {
+			status = http.StatusServiceUnavailable
+		}
+		render.RespondJSON(w, status, report)
+	})
+}
+
+// watchCancel marks the consumer as cancelled when the broker cancels it.
+func (cs *consumer) watchCancel(cancelled <-chan string) {
+	if _, ok := <-cancelled; ok {
+		cs.setStatus(ConsumerCancelled)
+	}
+}
+
+func (cs *consumer) setStatus(status string) {
+	cs.stateMu.Lock()
+	defer cs.stateMu.Unlock()
+	cs.status = status
+}
+
+// delivered records the time of the last delivery of the consumer.
+func (cs *consumer) delivered() {
+	cs.stateMu.Lock()
+	defer cs.stateMu.Unlock()
+	cs.lastDelivery = time.Now()
+}
+
+func (cs *consumer) health() ConsumerHealth {
+	cs.stateMu.Lock()
+	defer cs.stateMu.Unlock()
+
+	h := ConsumerHealth{
+		Name:   cs.tag,
+		Queue:  cs.queue,
+		Status: cs.status,
+	}
+	if !cs.lastDelivery.IsZero() {
+		lastDelivery := cs.lastDelivery
+		h.LastDeliveryAt = &lastDelivery
+	}
+
+	return h
+}
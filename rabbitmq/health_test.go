@@ -0,0 +1,98 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type healthStub struct {
+	RabbitMQ
+	report HealthReport
+}
+
+func (s *healthStub) Health(ctx context.Context) HealthReport {
+	return s.report
+}
+
+func TestHealthHandler(t *testing.T) {
+	t.Run("Should respond 200 with the report when the client is up", func(t *testing.T) {
+		report := HealthReport{
+			Status:     HealthUp,
+			Connection: ComponentHealth{OK: true},
+			Channel:    ComponentHealth{OK: true},
+			Consumers:  []ConsumerHealth{{Name: "consumer", Queue: "orders", Status: ConsumerActive}},
+		}
+		rec := httptest.NewRecorder()
+
+		HealthHandler(&healthStub{report: report}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+		var body HealthReport
+		json.NewDecoder(rec.Body).Decode(&body)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, report, body)
+	})
+	t.Run("Should respond 503 when the client is down", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+
+		HealthHandler(&healthStub{report: HealthReport{Status: HealthDown}}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+}
+
+func TestClientHealth(t *testing.T) {
+	t.Run("Should report a client without connection as down without panicking", func(t *testing.T) {
+		c := &Client{}
+
+		report := c.Health(context.Background())
+
+		assert.Equal(t, HealthDown, report.Status)
+		assert.False(t, report.Connection.OK)
+		assert.False(t, report.Channel.OK)
+		assert.NotNil(t, c.Ping())
+	})
+	t.Run("Should report the consumers that are not active", func(t *testing.T) {
+		active := &consumer{tag: "active", queue: "orders", status: ConsumerActive}
+		active.delivered()
+		cancelled := &consumer{tag: "cancelled", queue: "payments", status: ConsumerCancelled}
+		c := &Client{consumers: []*consumer{active, cancelled}}
+
+		report := c.Health(context.Background())
+
+		assert.Equal(t, HealthDown, report.Status)
+		assert.Len(t, report.Consumers, 2)
+		assert.Equal(t, ConsumerActive, report.Consumers[0].Status)
+		assert.NotNil(t, report.Consumers[0].LastDeliveryAt)
+		assert.Equal(t, ConsumerHealth{Name: "cancelled", Queue: "payments", Status: ConsumerCancelled}, report.Consumers[1])
+	})
+	t.Run("Should report every consumer while the consumers are removed", func(t *testing.T) {
+		consumers := []*consumer{
+			{tag: "orders", status: ConsumerActive},
+			{tag: "payments", status: ConsumerActive},
+			{tag: "refunds", status: ConsumerActive},
+		}
+		c := &Client{consumers: consumers}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			c.removeConsumer(consumers[0])
+		}()
+		report := c.Health(context.Background())
+		<-done
+
+		names := map[string]bool{}
+		for _, ch := range report.Consumers {
+			assert.False(t, names[ch.Name], "consumer %s reported twice", ch.Name)
+			names[ch.Name] = true
+		}
+		assert.Contains(t, names, "payments")
+		assert.Contains(t, names, "refunds")
+		assert.Equal(t, []*consumer{consumers[1], consumers[2]}, c.consumers)
+	})
+}
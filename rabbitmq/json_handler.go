@@ -0,0 +1,90 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+
+	"github.com/delivery-much/dm-go/logger"
+)
+
+// errNullPayload is returned when the body of a message with a pointer payload is null, that decodes to a nil pointer.
+var errNullPayload = errors.New("payload is null")
+
+// Validator represents a payload that can validate itself.
+// Payloads of a JSONHandler that implement it are validated before the handler is invoked.
+type Validator interface {
+	Validate() error
+}
+
+// JSONHandlerFunc signature of func to handle the decoded payload of a message
+type JSONHandlerFunc[T any] func(ctx context.Context, payload T, msg *Message) error
+
+// JSONHandler returns a SubscribeHandler that decodes the JSON body of the message into T
+// and then invokes the given handler with it.
+//
+// If T implements the Validator interface, the payload is validated after decoding.
+// Messages that cannot be decoded or are invalid are logged and rejected without invoking the handler, see Reject.
+// When T is a pointer, messages whose body is null are rejected as invalid, so the handler never receives a nil payload.
+//
+// Ex.:
+//
+//	type Order struct {
+//		ID string `json:"id"`
+//	}
+//
+//	func (o Order) Validate() error {
+//		if o.ID == "" {
+//			return errors.New("id is required")
+//		}
+//		return nil
+//	}
+//
+//	client.Subscribe(ctx, cg, rabbitmq.JSONHandler(func(ctx context.Context, order Order, msg *rabbitmq.Message) error {
+//		// business logic
+//		return nil
+//	}))
+func JSONHandler[T any](handler JSONHandlerFunc[T]) SubscribeHandler {
+	return func(ctx context.Context, msg *Message) error {
+		var payload T
+		err := json.Unmarshal(msg.Body, &payload)
+		if err != nil {
+			logger.Errorw(ctx, "Failed to decode message",
+				"error", err.Error(),
+				"exchange", msg.Delivery.Exchange,
+				"routing_key", msg.Delivery.RoutingKey,
+				"message_id", msg.Delivery.MessageId,
+			)
+			return Reject(err)
+		}
+
+		err = validate(&payload)
+		if err != nil {
+			logger.Errorw(ctx, "Invalid message",
+				"error", err.Error(),
+				"exchange", msg.Delivery.Exchange,
+				"routing_key", msg.Delivery.RoutingKey,
+				"message_id", msg.Delivery.MessageId,
+			)
+			return Reject(err)
+		}
+
+		return handler(ctx, payload, msg)
+	}
+}
+
+// validate validates the payload if it, or a pointer to it, implements the Validator interface.
+// A nil pointer payload, decoded from a null body, is invalid.
+func validate[T any](payload *T) error {
+	if v := reflect.ValueOf(any(*payload)); v.Kind() == reflect.Pointer && v.IsNil() {
+		return errNullPayload
+	}
+	if v, ok := any(*payload).(Validator); ok {
+		return v.Validate()
+	}
+	if v, ok := any(payload).(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type orderMock struct {
+	ID string `json:"id"`
+}
+
+func (o *orderMock) Validate() error {
+	if o.ID == "" {
+		return errors.New("id is required")
+	}
+	return nil
+}
+
+func TestJSONHandler(t *testing.T) {
+	t.Run("Should decode the body and invoke the handler", func(t *testing.T) {
+		var received orderMock
+		h := JSONHandler(func(ctx context.Context, order orderMock, msg *Message) error {
+			received = order
+			return nil
+		})
+
+		err := h(context.Background(), &Message{Body: []byte(`{"id": "123"}`)})
+
+		assert.Nil(t, err)
+		assert.Equal(t, orderMock{ID: "123"}, received)
+	})
+	t.Run("Should return the error of the handler", func(t *testing.T) {
+		errMock := errors.New("handler error")
+		h := JSONHandler(func(ctx context.Context, order orderMock, msg *Message) error {
+			return errMock
+		})
+
+		err := h(context.Background(), &Message{Body: []byte(`{"id": "123"}`)})
+
+		assert.Equal(t, errMock, err)
+		assert.False(t, errors.Is(err, ErrRejected))
+	})
+	t.Run("Should reject a malformed message without invoking the handler", func(t *testing.T) {
+		called := false
+		h := JSONHandler(func(ctx context.Context, order orderMock, msg *Message) error {
+			called = true
+			return nil
+		})
+
+		err := h(context.Background(), &Message{Body: []byte(`{"id": `)})
+
+		assert.True(t, errors.Is(err, ErrRejected))
+		assert.False(t, called)
+	})
+	t.Run("Should reject an invalid message without invoking the handler", func(t *testing.T) {
+		called := false
+		h := JSONHandler(func(ctx context.Context, order orderMock, msg *Message) error {
+			called = true
+			return nil
+		})
+
+		err := h(context.Background(), &Message{Body: []byte(`{}`)})
+
+		assert.True(t, errors.Is(err, ErrRejected))
+		assert.EqualError(t, err, "message rejected: id is required")
+		assert.False(t, called)
+	})
+	t.Run("Should reject a null body of a pointer payload without invoking the handler", func(t *testing.T) {
+		called := false
+		h := JSONHandler(func(ctx context.Context, order *orderMock, msg *Message) error {
+			called = true
+			return nil
+		})
+
+		err := h(context.Background(), &Message{Body: []byte(`null`)})
+
+		assert.True(t, errors.Is(err, ErrRejected))
+		assert.EqualError(t, err, "message rejected: payload is null")
+		assert.False(t, called)
+	})
+	t.Run("Should validate pointer payloads", func(t *testing.T) {
+		h := JSONHandler(func(ctx context.Context, order *orderMock, msg *Message) error {
+			return nil
+		})
+
+		err := h(context.Background(), &Message{Body: []byte(`{}`)})
+
+		assert.True(t, errors.Is(err, ErrRejected))
+	})
+}
@@ -0,0 +1,133 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// meterName is the name of the OpenTelemetry meter of the package.
+const meterName = "github.com/delivery-much/dm-go/rabbitmq"
+
+// instruments represents the OpenTelemetry instruments of the package.
+//
+// They are created from the global MeterProvider, so the metrics are only exported
+// when the application registers one through otel.SetMeterProvider.
+type instruments struct {
+	consumed        metric.Int64Counter
+	acked           metric.Int64Counter
+	nacked          metric.Int64Counter
+	redelivered     metric.Int64Counter
+	inFlight        metric.Int64UpDownCounter
+	handlerDuration metric.Float64Histogram
+	published       metric.Int64Counter
+	publishFailures metric.Int64Counter
+	publishDuration metric.Float64Histogram
+	reconnects      metric.Int64Counter
+}
+
+var (
+	metricsOnce sync.Once
+	metrics     instruments
+)
+
+// getMetrics returns the instruments of the package, creating them on the first call.
+func getMetrics() *instruments {
+	metricsOnce.Do(func() {
+		meter := otel.Meter(meterName)
+
+		var errs [10]error
+		metrics.consumed, errs[0] = meter.Int64Counter("rabbitmq.consumer.messages.consumed",
+			metric.WithDescription("Number of messages delivered to the consumers"))
+		metrics.acked, errs[1] = meter.Int64Counter("rabbitmq.consumer.messages.acked",
+			metric.WithDescription("Number of messages successfully handled and acknowledged"))
+		metrics.nacked, errs[2] = meter.Int64Counter("rabbitmq.consumer.messages.nacked",
+			metric.WithDescription("Number of messages whose handler failed, retried or rejected"))
+		metrics.redelivered, errs[3] = meter.Int64Counter("rabbitmq.consumer.messages.redelivered",
+			metric.WithDescription("Number of messages delivered again by the broker"))
+		metrics.inFlight, errs[4] = meter.Int64UpDownCounter("rabbitmq.consumer.messages.in_flight",
+			metric.WithDescription("Number of messages being handled"))
+		metrics.handlerDuration, errs[5] = meter.Float64Histogram("rabbitmq.consumer.handler.duration",
+			metric.WithDescription("Duration of the message handlers"),
+			metric.WithUnit("s"))
+		metrics.published, errs[6] = meter.Int64Counter("rabbitmq.publisher.messages.published",
+			metric.WithDescription("Number of messages successfully published"))
+		metrics.publishFailures, errs[7] = meter.Int64Counter("rabbitmq.publisher.messages.failed",
+			metric.WithDescription("Number of messages that failed to be published"))
+		metrics.publishDuration, errs[8] = meter.Float64Histogram("rabbitmq.publisher.publish.duration",
+			metric.WithDescription("Duration of the publishes, including the broker confirmation"),
+			metric.WithUnit("s"))
+		metrics.reconnects, errs[9] = meter.Int64Counter("rabbitmq.connection.reconnects",
+			metric.WithDescription("Number of times the clients reconnected to the broker"))
+
+		if err := errors.Join(errs[:]...); err != nil {
+			otel.Handle(err)
+		}
+	})
+
+	return &metrics
+}
+
+// consumerAttributes returns the metric attributes of a consumer.
+// The consumer name is only set when configured, since the generated tags would create a series per consumer.
+func consumerAttributes(exchange, queue, consumer string) metric.MeasurementOption {
+	attrs := []attribute.KeyValue{
+		attribute.String("messaging.destination.name", exchange),
+		attribute.String("messaging.rabbitmq.queue", queue),
+	}
+	if consumer != "" {
+		attrs = append(attrs, attribute.String("messaging.consumer.name", consumer))
+	}
+
+	return metric.WithAttributeSet(attribute.NewSet(attrs...))
+}
+
+// publisherAttributes returns the metric attributes of a publish.
+func publisherAttributes(exchange string) metric.MeasurementOption {
+	return metric.WithAttributeSet(attribute.NewSet(
+		attribute.String("messaging.destination.name", exchange),
+	))
+}
+
+// startHandling records a message delivered to a consumer,
+// returning the function that records the end of its handling.
+func (m *instruments) startHandling(ctx context.Context, attrs metric.MeasurementOption, redelivered bool) func() {
+	m.consumed.Add(ctx, 1, attrs)
+	if redelivered {
+		m.redelivered.Add(ctx, 1, attrs)
+	}
+
+	m.inFlight.Add(ctx, 1, attrs)
+	start := time.Now()
+
+	return func() {
+		m.handlerDuration.Record(ctx, time.Since(start).Seconds(), attrs)
+		m.inFlight.Add(ctx, -1, attrs)
+	}
+}
+
+// settled records the outcome of a handled message.
+func (m *instruments) settled(ctx context.Context, attrs metric.MeasurementOption, err error) {
+	if err != nil {
+		m.nacked.Add(ctx, 1, attrs)
+		return
+	}
+	m.acked.Add(ctx, 1, attrs)
+}
+
+// recordPublish records the outcome and the duration of a publish.
+func (m *instruments) recordPublish(ctx context.Context, exchange string, start time.Time, err error) {
+	attrs := publisherAttributes(exchange)
+	m.publishDuration.Record(ctx, time.Since(start).Seconds(), attrs)
+
+	if err != nil {
+		m.publishFailures.Add(ctx, 1, attrs)
+		return
+	}
+	m.published.Add(ctx, 1, attrs)
+}
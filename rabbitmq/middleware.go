@@ -0,0 +1,85 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/delivery-much/dm-go/logger"
+)
+
+// HandlerMiddleware signature of func to wrap a SubscribeHandler with cross-cutting behaviour,
+// the same way HTTP middlewares wrap an http.Handler.
+//
+// Ex.:
+//
+//	func Timing(next rabbitmq.SubscribeHandler) rabbitmq.SubscribeHandler {
+//		return func(ctx context.Context, msg *rabbitmq.Message) error {
+//			start := time.Now()
+//			defer func() { fmt.Println(time.Since(start)) }()
+//			return next(ctx, msg)
+//		}
+//	}
+type HandlerMiddleware func(next SubscribeHandler) SubscribeHandler
+
+// chain wraps the handler with the middlewares, the first middleware being the outermost one.
+func chain(handler SubscribeHandler, middlewares ...HandlerMiddleware) SubscribeHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// Use appends middlewares to the chain of the client,
+// that wraps the handlers of every consumer subscribed after the call, around the middlewares of the consumer.
+func (c *Client) Use(middlewares ...HandlerMiddleware) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
+// Recoverer is a middleware that recovers from panics in the handler,
+// logging the panic and its stack trace and returning it as an error, so the message is retried or rejected.
+func Recoverer(next SubscribeHandler) SubscribeHandler {
+	return func(ctx context.Context, msg *Message) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Errorw(ctx, "Recovered from panic while handling message",
+					"panic", fmt.Sprint(r),
+					"stack", string(debug.Stack()),
+					"exchange", msg.Delivery.Exchange,
+					"routing_key", msg.Delivery.RoutingKey,
+					"message_id", msg.Delivery.MessageId,
+				)
+				err = fmt.Errorf("panic while handling message: %v", r)
+			}
+		}()
+
+		return next(ctx, msg)
+	}
+}
+
+// Logger is a middleware that logs every handled message and how long the handler took,
+// at the info level when the handler succeeds and at the error level when it fails.
+func Logger(next SubscribeHandler) SubscribeHandler {
+	return func(ctx context.Context, msg *Message) error {
+		start := time.Now()
+		err := next(ctx, msg)
+
+		keysAndValues := []any{
+			"exchange", msg.Delivery.Exchange,
+			"routing_key", msg.Delivery.RoutingKey,
+			"message_id", msg.Delivery.MessageId,
+			"duration", time.Since(start).String(),
+		}
+		if err != nil {
+			logger.Errorw(ctx, "Failed to handle message", append(keysAndValues, "error", err.Error())...)
+			return err
+		}
+
+		logger.Infow(ctx, "Message handled", keysAndValues...)
+		return nil
+	}
+}
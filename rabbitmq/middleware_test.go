@@ -0,0 +1,62 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChain(t *testing.T) {
+	t.Run("Should call the middlewares in order, the first being the outermost", func(t *testing.T) {
+		calls := []string{}
+		mw := func(name string) HandlerMiddleware {
+			return func(next SubscribeHandler) SubscribeHandler {
+				return func(ctx context.Context, msg *Message) error {
+					calls = append(calls, name+" before")
+					err := next(ctx, msg)
+					calls = append(calls, name+" after")
+					return err
+				}
+			}
+		}
+		h := func(ctx context.Context, msg *Message) error {
+			calls = append(calls, "handler")
+			return nil
+		}
+
+		err := chain(h, mw("first"), mw("second"))(context.Background(), &Message{})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"first before", "second before", "handler", "second after", "first after"}, calls)
+	})
+	t.Run("Should return the handler itself when there are no middlewares", func(t *testing.T) {
+		errMock := errors.New("handler error")
+		h := func(ctx context.Context, msg *Message) error {
+			return errMock
+		}
+
+		assert.Equal(t, errMock, chain(h)(context.Background(), &Message{}))
+	})
+}
+
+func TestRecoverer(t *testing.T) {
+	t.Run("Should return the panic as an error", func(t *testing.T) {
+		h := Recoverer(func(ctx context.Context, msg *Message) error {
+			panic("boom")
+		})
+
+		err := h(context.Background(), &Message{})
+
+		assert.EqualError(t, err, "panic while handling message: boom")
+	})
+	t.Run("Should return the error of the handler when it does not panic", func(t *testing.T) {
+		errMock := errors.New("handler error")
+		h := Recoverer(func(ctx context.Context, msg *Message) error {
+			return errMock
+		})
+
+		assert.Equal(t, errMock, h(context.Background(), &Message{}))
+	})
+}
@@ -0,0 +1,102 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/delivery-much/dm-go/middleware"
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader is the message header that carries the request id from the publisher to the consumers.
+const RequestIDHeader = "x-request-id"
+
+// headerCarrier adapts the message headers to the OpenTelemetry TextMapCarrier,
+// so the trace context can be injected in and extracted from them.
+type headerCarrier amqp.Table
+
+func (hc headerCarrier) Get(key string) string {
+	v, _ := hc[key].(string)
+	return v
+}
+
+func (hc headerCarrier) Set(key, value string) {
+	hc[key] = value
+}
+
+func (hc headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(hc))
+	for k := range hc {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// injectContext returns a copy of the headers with the trace context and the request id of the context.
+func injectContext(ctx context.Context, headers amqp.Table) amqp.Table {
+	injected := amqp.Table{}
+	for k, v := range headers {
+		injected[k] = v
+	}
+
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier(injected))
+
+	if reqID := middleware.GetReqID(ctx); reqID != "" {
+		injected[RequestIDHeader] = reqID
+	}
+
+	return injected
+}
+
+// extractContext returns a copy of the context with the trace context and the request id found in the headers.
+func extractContext(ctx context.Context, headers amqp.Table) context.Context {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, headerCarrier(headers))
+
+	if reqID, ok := headers[RequestIDHeader].(string); ok && reqID != "" {
+		ctx = context.WithValue(ctx, middleware.RequestIDKey, reqID)
+	}
+
+	return ctx
+}
+
+// deliveryAttributes returns the span attributes of a consumed message.
+func deliveryAttributes(queue string, d amqp.Delivery) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("messaging.system", "rabbitmq"),
+		attribute.String("messaging.operation", "process"),
+		attribute.String("messaging.destination.name", d.Exchange),
+		attribute.String("messaging.rabbitmq.destination.routing_key", d.RoutingKey),
+		attribute.String("messaging.rabbitmq.queue", queue),
+		attribute.String("messaging.message.id", d.MessageId),
+	}
+}
+
+// publishingAttributes returns the span attributes of a published message.
+func publishingAttributes(exchange, routingKey string, msg amqp.Publishing) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("messaging.system", "rabbitmq"),
+		attribute.String("messaging.operation", "publish"),
+		attribute.String("messaging.destination.name", exchange),
+		attribute.String("messaging.rabbitmq.destination.routing_key", routingKey),
+		attribute.String("messaging.message.id", msg.MessageId),
+	}
+}
+
+// spanName returns the name of a messaging span, following the OpenTelemetry conventions.
+func spanName(destination, operation string) string {
+	if destination == "" {
+		destination = "(default)"
+	}
+	return fmt.Sprintf("%s %s", destination, operation)
+}
+
+// recordSpanError marks the span of the context as failed with the given error.
+func recordSpanError(ctx context.Context, err error) {
+	span := oteltrace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
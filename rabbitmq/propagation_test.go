@@ -0,0 +1,63 @@
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/delivery-much/dm-go/middleware"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestContextPropagation(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	traceID, _ := oteltrace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := oteltrace.SpanIDFromHex("00f067aa0ba902b7")
+	sc := oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: oteltrace.FlagsSampled,
+	})
+
+	t.Run("Should inject the trace context and the request id without changing the original headers", func(t *testing.T) {
+		ctx := oteltrace.ContextWithSpanContext(context.Background(), sc)
+		ctx = context.WithValue(ctx, middleware.RequestIDKey, "req-id")
+		original := amqp.Table{"event": "order.created"}
+
+		headers := injectContext(ctx, original)
+
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", headers["traceparent"])
+		assert.Equal(t, "req-id", headers[RequestIDHeader])
+		assert.Equal(t, "order.created", headers["event"])
+		assert.Equal(t, amqp.Table{"event": "order.created"}, original)
+	})
+	t.Run("Should not inject anything when the context has no trace or request id", func(t *testing.T) {
+		headers := injectContext(context.Background(), nil)
+
+		assert.Empty(t, headers)
+	})
+	t.Run("Should extract the trace context and the request id from the headers", func(t *testing.T) {
+		headers := injectContext(
+			context.WithValue(oteltrace.ContextWithSpanContext(context.Background(), sc), middleware.RequestIDKey, "req-id"),
+			nil,
+		)
+
+		ctx := extractContext(context.Background(), headers)
+
+		extracted := oteltrace.SpanContextFromContext(ctx)
+		assert.Equal(t, traceID, extracted.TraceID())
+		assert.Equal(t, spanID, extracted.SpanID())
+		assert.True(t, extracted.IsRemote())
+		assert.Equal(t, "req-id", middleware.GetReqID(ctx))
+	})
+	t.Run("Should return the context unchanged when the headers are empty", func(t *testing.T) {
+		ctx := extractContext(context.Background(), nil)
+
+		assert.False(t, oteltrace.SpanContextFromContext(ctx).IsValid())
+		assert.Equal(t, "", middleware.GetReqID(ctx))
+	})
+}
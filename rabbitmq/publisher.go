@@ -0,0 +1,179 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/delivery-much/dm-go/optel"
+	"github.com/streadway/amqp"
+)
+
+// ErrNotConfirmed is returned when the broker does not confirm a message published with PublishWithConfirm.
+var ErrNotConfirmed = errors.New("message not confirmed by the broker")
+
+// publishChannel represents a channel shared by the publishes of the client, reopened when it is closed.
+// In confirm mode, the publishes are serialized, each one waiting for its confirmation.
+type publishChannel struct {
+	confirm bool
+
+	mu sync.Mutex
+	// conn is the connection the channel was opened in
+	conn     *amqp.Connection
+	ch       *amqp.Channel
+	confirms chan amqp.Confirmation
+}
+
+// Publish publishes a message in the exchange with the given routing key.
+// The priority of the message, for priority queues, see QueueOptions.MaxPriority,
+// and its expiration, see Expiration, are set in the amqp.Publishing.
+// The trace context and the request id found in the context are injected in the message headers,
+// so the consumers of the message continue the same trace.
+//
+// Ex.:
+//
+//	err := client.Publish(ctx, "orders", "order.created", amqp.Publishing{
+//		ContentType:  "application/json",
+//		DeliveryMode: amqp.Persistent,
+//		Body:         body,
+//	})
+func (c *Client) Publish(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) error {
+	return c.publish(ctx, &c.publisher, exchange, routingKey, msg)
+}
+
+// PublishWithConfirm publishes a message like Publish, but waits for the broker to confirm it,
+// returning ErrNotConfirmed if the broker rejects the message,
+// or the context error if the context is done before the confirmation.
+func (c *Client) PublishWithConfirm(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) error {
+	return c.publish(ctx, &c.confirmPublisher, exchange, routingKey, msg)
+}
+
+// Expiration returns the Expiration property of a message that expires after the given TTL,
+// rounded to milliseconds and at least 1 millisecond, since an expiration of 0 expires the message right away.
+// Expired messages are dropped, or dead-lettered if the queue has a dead-letter exchange.
+//
+// Ex.:
+//
+//	err := client.Publish(ctx, "notifications", "push.send", amqp.Publishing{
+//		Priority:   9,
+//		Expiration: rabbitmq.Expiration(30 * time.Second),
+//		Body:       body,
+//	})
+func Expiration(ttl time.Duration) string {
+	ms := ttl.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	return strconv.FormatInt(ms, 10)
+}
+
+func (c *Client) publish(ctx context.Context, pc *publishChannel, exchange, routingKey string, msg amqp.Publishing) error {
+	ctx, end := optel.StartTrackProducer(ctx, spanName(exchange, "publish"), publishingAttributes(exchange, routingKey, msg)...)
+	defer end()
+
+	msg.Headers = injectContext(ctx, msg.Headers)
+
+	start := time.Now()
+	err := pc.publish(ctx, c.connection(), exchange, routingKey, msg)
+	getMetrics().recordPublish(ctx, exchange, start, err)
+	if err != nil {
+		recordSpanError(ctx, err)
+		return err
+	}
+
+	return nil
+}
+
+func (pc *publishChannel) publish(ctx context.Context, conn *amqp.Connection, exchange, routingKey string, msg amqp.Publishing) error {
+	pc.mu.Lock()
+	ch, err := pc.channel(conn)
+	if err != nil {
+		pc.mu.Unlock()
+		return err
+	}
+
+	if !pc.confirm {
+		// plain publishes are safe to be done concurrently in the same channel
+		pc.mu.Unlock()
+		return publishOn(ch, exchange, routingKey, msg)
+	}
+
+	defer pc.mu.Unlock()
+
+	err = publishOn(ch, exchange, routingKey, msg)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case confirmation, ok := <-pc.confirms:
+		if !ok {
+			return fmt.Errorf("Failed to publish a message: %s", amqp.ErrClosed)
+		}
+		if !confirmation.Ack {
+			return ErrNotConfirmed
+		}
+		return nil
+	case <-ctx.Done():
+		// the confirmation may still arrive, so the channel is discarded
+		// to not take it as the confirmation of the next message
+		pc.ch.Close()
+		pc.ch = nil
+		return ctx.Err()
+	}
+}
+
+func publishOn(ch *amqp.Channel, exchange, routingKey string, msg amqp.Publishing) error {
+	err := ch.Publish(
+		exchange,   // exchange
+		routingKey, // routing key
+		false,      // mandatory
+		false,      // immediate
+		msg,        // message
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to publish a message: %s", err)
+	}
+
+	return nil
+}
+
+// channel returns the channel of the publisher, opening a new one if it was not opened yet, if it was closed
+// or if the client reconnected. It must be called holding the publisher lock.
+func (pc *publishChannel) channel(conn *amqp.Connection) (*amqp.Channel, error) {
+	if pc.ch != nil && pc.conn == conn {
+		return pc.ch, nil
+	}
+
+	ch, err := openChannel(conn)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open a channel: %s", err)
+	}
+
+	if pc.confirm {
+		err = ch.Confirm(false)
+		if err != nil {
+			ch.Close()
+			return nil, fmt.Errorf("Failed to put the channel in confirm mode: %s", err)
+		}
+		pc.confirms = ch.NotifyPublish(make(chan amqp.Confirmation, 1))
+	}
+
+	closed := ch.NotifyClose(make(chan *amqp.Error, 1))
+	go func() {
+		<-closed
+
+		pc.mu.Lock()
+		defer pc.mu.Unlock()
+		if pc.ch == ch {
+			pc.ch = nil
+		}
+	}()
+
+	pc.conn = conn
+	pc.ch = ch
+	return ch, nil
+}
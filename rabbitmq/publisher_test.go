@@ -0,0 +1,19 @@
+package rabbitmq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiration(t *testing.T) {
+	t.Run("Should format the TTL in milliseconds", func(t *testing.T) {
+		assert.Equal(t, "30000", Expiration(30*time.Second))
+		assert.Equal(t, "1500", Expiration(1500*time.Millisecond))
+	})
+	t.Run("Should expire after at least 1 millisecond", func(t *testing.T) {
+		assert.Equal(t, "1", Expiration(0))
+		assert.Equal(t, "1", Expiration(-time.Second))
+	})
+}
@@ -2,13 +2,22 @@ package rabbitmq
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
+	"slices"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/streadway/amqp"
 )
 
+// ErrShuttingDown is returned when subscribing in a client that is shutting down.
+var ErrShuttingDown = errors.New("rabbitmq client is shutting down")
+
 // SubscribeHandler signature of func to handler/execute in sub message
 type SubscribeHandler func(ctx context.Context, msg *Message) error
 
@@ -16,31 +25,70 @@ type SubscribeHandler func(ctx context.Context, msg *Message) error
 type Message struct {
 	Delivery amqp.Delivery
 	Body     []byte
+	// Priority is the priority the message was published with, 0 when it has none.
+	Priority uint8
+	// Expiration is the expiration the message was published with, 0 when it has none, see Expiration.
+	Expiration time.Duration
+}
+
+// newMessage returns the Message of a delivery, with its properties in typed form.
+func newMessage(d amqp.Delivery) *Message {
+	msg := &Message{
+		Delivery: d,
+		Body:     d.Body,
+		Priority: d.Priority,
+	}
+
+	if ms, err := strconv.ParseInt(d.Expiration, 10, 64); err == nil {
+		msg.Expiration = time.Duration(ms) * time.Millisecond
+	}
+
+	return msg
 }
 
 // RabbitMQ represents the functions to connect and subribe in RabbitMQ
 type RabbitMQ interface {
 	Close()
+	Shutdown(ctx context.Context) error
 	Ping() error
-	Subscribe(cg ConsumerConfig, subHandler SubscribeHandler) error
+	Subscribe(ctx context.Context, cg ConsumerConfig, subHandler SubscribeHandler) error
+	Publish(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) error
+	PublishWithConfirm(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) error
+	Call(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) (*Message, error)
+	Use(middlewares ...HandlerMiddleware)
+	Health(ctx context.Context) HealthReport
 }
 
 // Client represents the client with connection to RabbitMQ.
 type Client struct {
-	conn *amqp.Connection
+	config Config
+	tls    *tls.Config
+
+	// connMu guards the connection, replaced when the client reconnects
+	connMu  sync.RWMutex
+	conn    *amqp.Connection
+	closed  bool
+	closing chan struct{}
+
+	mu           sync.Mutex
+	consumers    []*consumer
+	middlewares  []HandlerMiddleware
+	shuttingDown bool
+
+	publisher        publishChannel
+	confirmPublisher publishChannel
+	rpc              rpcChannel
 }
 
 // New Connect and returns the AMQP Client that implements the AMQP interface.
+// If the broker is not available, the client is returned along with the error, without a connection,
+// and keeps trying to connect in the background until it is closed.
+// For TLS, more brokers and other connection options, see NewWithConfig.
 func New(amqpURI, projectName string) (RabbitMQ, error) {
-	c := &Client{}
-	var err error
-	cfg := amqp.Config{
-		Properties: amqp.Table{
-			"connection_name": projectName,
-		},
-	}
-	c.conn, err = amqp.DialConfig(amqpURI, cfg)
-	return c, err
+	return NewWithConfig(Config{
+		URIs:        []string{amqpURI},
+		ProjectName: projectName,
+	})
 }
 
 // ConsumerConfig represents all configs to create and configure a subscribe/consumer
@@ -50,62 +98,118 @@ type ConsumerConfig struct {
 	QueueName    string
 	BindingKey   string
 	ConsumerName string
+	// Bindings optionally binds the queue with more keys, or with arguments for headers exchanges.
+	// When set, the BindingKey is only bound if it is not empty.
+	Bindings []Binding
+	// Exchange and Queue are the options used to declare the exchange and the queue.
+	Exchange ExchangeOptions
+	Queue    QueueOptions
+	// Passive only checks that the exchange, the queue and the retry topology exist, without declaring or binding them,
+	// for topologies provisioned outside of the application.
+	Passive bool
+	// Middlewares wrap the handler of the consumer, inside the middlewares of the client, see Client.Use.
+	Middlewares []HandlerMiddleware
+	// HandlerTimeout is the timeout of the context of each message handled. Default: 10 seconds.
+	HandlerTimeout time.Duration
+	// Prefetch is the maximum number of unacknowledged messages the broker delivers to the consumer,
+	// bounding the messages held in memory by the client. Default: 10.
+	Prefetch int
+	// Retry optionally declares a retry topology for the queue, see RetryConfig.
+	// When nil, messages whose handler returns an error are rejected without requeue.
+	Retry *RetryConfig
 }
 
 // Subscribe subscribe in a queue in exchange to consume events that is published in her.
 // Open a new channel in the client connection.
-// Passing the parameters of name of exchange, type of exchange (direct, topic or fanout), queue name, binding key.
+// Passing the parameters of name of exchange, type of exchange (direct, topic or fanout), queue name, binding key,
+// and optionally the declaration options of the exchange and queue, see ExchangeOptions and QueueOptions.
 // And a handler function to execute in consume, where stay the business logic for execute when the event is received.
-func (c *Client) Subscribe(cg ConsumerConfig, subHandler SubscribeHandler) error {
-	ch, err := c.conn.Channel()
+// The handler context carries the trace context and the request id published with the message, see Publish.
+// The message is acknowledged when the handler returns nil, otherwise it is retried or rejected, see RetryConfig.
+//
+// The consumer is cancelled when the given context is done, after the in-flight message is handled.
+// The messages already delivered to the client but not handled yet are left unacknowledged and requeued by the broker.
+// The handler contexts carry the values of the given context, but are not cancelled with it.
+// When the client reconnects, the consumer is subscribed again with the same config.
+func (c *Client) Subscribe(ctx context.Context, cg ConsumerConfig, subHandler SubscribeHandler) error {
+	c.mu.Lock()
+	if c.shuttingDown {
+		c.mu.Unlock()
+		return ErrShuttingDown
+	}
+	handler := chain(chain(subHandler, cg.Middlewares...), c.middlewares...)
+	c.mu.Unlock()
+
+	return c.subscribe(ctx, cg, handler)
+}
+
+// subscribe declares the topology of the consumer and starts consuming with the handler, already wrapped by the middlewares.
+// It must be called without holding the client lock, which is taken only to register the consumer,
+// so the other calls of the client are not blocked by the declarations in the broker.
+func (c *Client) subscribe(ctx context.Context, cg ConsumerConfig, subHandler SubscribeHandler) (err error) {
+	ch, err := openChannel(c.connection())
 	if err != nil {
 		return fmt.Errorf("Failed to open a channel: %s", err)
 	}
+	// closed when any step fails, so a failed subscribe does not leak the channel in the broker
+	defer func() {
+		if err != nil {
+			ch.Close()
+		}
+	}()
 
-	err = ch.ExchangeDeclare(
-		cg.ExchangeName, // name of the exchange
-		cg.ExchangeType, // type
-		true,            // durable
-		false,           // delete when complete
-		false,           // internal
-		false,           // noWait
-		nil,             // arguments
-	)
+	err = declareExchange(ch, cg)
 	if err != nil {
-		return fmt.Errorf("Failed to register an Exchange: %s", err)
+		return err
 	}
 
-	queue, err := ch.QueueDeclare(
-		cg.QueueName, // name of the queue
-		true,         // durable
-		false,        // delete when usused
-		false,        // exclusive
-		false,        // noWait
-		nil,          // arguments
-	)
+	queue, err := declareQueue(ch, cg)
 	if err != nil {
-		return fmt.Errorf("Failed to register an Queue: %s", err)
+		return err
 	}
 
-	err = ch.QueueBind(
-		queue.Name,      // name of the queue
-		cg.BindingKey,   // bindingKey
-		cg.ExchangeName, // sourceExchange
-		false,           // noWait
-		nil,             // arguments
-	)
+	var rt *retryTopology
+	var confirms chan amqp.Confirmation
+	if cg.Retry != nil {
+		rt, err = declareRetryTopology(ch, queue.Name, *cg.Retry, cg.Passive)
+		if err != nil {
+			return err
+		}
+
+		// the failed messages are moved in confirm mode, to be acknowledged only once the broker has the moved copy
+		err = ch.Confirm(false)
+		if err != nil {
+			return fmt.Errorf("Failed to put the channel in confirm mode: %s", err)
+		}
+		confirms = ch.NotifyPublish(make(chan amqp.Confirmation, 1))
+	}
+
+	prefetch := cg.Prefetch
+	if prefetch <= 0 {
+		prefetch = defaultPrefetch
+	}
+	err = ch.Qos(prefetch, 0, false)
 	if err != nil {
-		return fmt.Errorf("Queue Bind: %s", err)
+		return fmt.Errorf("Failed to set the prefetch: %s", err)
 	}
 
+	// the consumer tag must be known to cancel the consumer on shutdown
+	tag := cg.ConsumerName
+	if tag == "" {
+		tag = uuid.New().String()
+	}
+
+	// registered before consuming, to not miss a cancel sent by the broker right after the consume
+	cancelled := ch.NotifyCancel(make(chan string, 1))
+
 	msgs, err := ch.Consume(
-		queue.Name,      // queue
-		cg.ConsumerName, // tag
-		true,            // auto-ack
-		false,           // exclusive
-		false,           // no-local
-		false,           // no-wait
-		nil,             // args
+		queue.Name, // queue
+		tag,        // tag
+		false,      // auto-ack
+		false,      // exclusive
+		false,      // no-local
+		false,      // no-wait
+		nil,        // args
 	)
 	if err != nil {
 		return fmt.Errorf("Failed to register a consumer: %s", err)
@@ -113,34 +217,136 @@ func (c *Client) Subscribe(cg ConsumerConfig, subHandler SubscribeHandler) error
 
 	log.Printf("Consumer registered: exchange %s, queue_name %s, routing_key %s consumer_name %s", cg.ExchangeName, cg.QueueName, cg.BindingKey, cg.ConsumerName)
 
-	go consumeLoop(msgs, subHandler)
+	timeout := cg.HandlerTimeout
+	if timeout <= 0 {
+		timeout = defaultHandlerTimeout
+	}
+
+	cs := &consumer{
+		tag:         tag,
+		queue:       queue.Name,
+		ch:          ch,
+		confirms:    confirms,
+		ctx:         ctx,
+		cg:          cg,
+		subHandler:  subHandler,
+		retry:       rt,
+		baseCtx:     context.WithoutCancel(ctx),
+		timeout:     timeout,
+		metricAttrs: consumerAttributes(cg.ExchangeName, queue.Name, cg.ConsumerName),
+		status:      ConsumerActive,
+		stopping:    make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+
+	c.mu.Lock()
+	if c.shuttingDown {
+		// closing the channel cancels the consumer and requeues the messages already delivered to it
+		c.mu.Unlock()
+		return ErrShuttingDown
+	}
+	c.consumers = append(c.consumers, cs)
+	c.mu.Unlock()
+
+	go cs.consumeLoop(msgs)
+	go cs.watchCancel(cancelled)
+	go c.cancelOnDone(ctx, cs)
+	return nil
+}
+
+// cancelOnDone cancels the consumer when the context is done,
+// closing its channel after the in-flight message is settled, which requeues the deliveries that were not handled.
+func (c *Client) cancelOnDone(ctx context.Context, cs *consumer) {
+	select {
+	case <-ctx.Done():
+	case <-cs.done:
+		return
+	}
+
+	cs.stop()
+	cs.ch.Cancel(cs.tag, false)
+	<-cs.done
+	cs.ch.Close()
+	c.removeConsumer(cs)
+}
+
+// removeConsumer removes the consumer from the client.
+// The consumers are copied to a new slice, since the previous one may still be read after the lock is released.
+func (c *Client) removeConsumer(cs *consumer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.consumers = slices.DeleteFunc(slices.Clone(c.consumers), func(registered *consumer) bool {
+		return registered == cs
+	})
+}
+
+// Shutdown gracefully stops the client.
+// It cancels every consumer so no new message is handled, waits for the in-flight handlers to finish
+// and then closes the channels and the connection.
+// The messages already delivered to the client but not handled yet, up to the Prefetch of each consumer,
+// are left unacknowledged and requeued by the broker when the channels are closed.
+// If the context is done before the handlers finish, the channels and the connection are closed anyway,
+// the unacknowledged messages are requeued by the broker and the context error is returned.
+func (c *Client) Shutdown(ctx context.Context) (err error) {
+	c.mu.Lock()
+	c.shuttingDown = true
+	consumers := c.consumers
+	c.consumers = nil
+	c.mu.Unlock()
+
+	for _, cs := range consumers {
+		cs.stop()
+		cErr := cs.ch.Cancel(cs.tag, false)
+		if cErr != nil {
+			err = errors.Join(err, fmt.Errorf("Failed to cancel consumer %s: %s", cs.tag, cErr))
+		}
+	}
+
+	err = errors.Join(err, waitConsumers(ctx, consumers))
+
+	for _, cs := range consumers {
+		cs.ch.Close()
+	}
+	c.Close()
+	return
+}
+
+// waitConsumers waits for the consume loop of every consumer to return, or for the context to be done.
+func waitConsumers(ctx context.Context, consumers []*consumer) error {
+	for _, cs := range consumers {
+		select {
+		case <-cs.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 	return nil
 }
 
 // Close will close the connection.
+// It does not wait for the in-flight messages to be handled, see Shutdown.
+// A closed client does not reconnect.
 func (c *Client) Close() {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+
+	if !c.closed && c.closing != nil {
+		close(c.closing)
+	}
+	c.closed = true
+
 	if c.conn != nil {
 		c.conn.Close()
 	}
 }
 
 // Ping get the status of connection with RabbitMQ
+// For a detailed report of the client, see Health.
 func (c *Client) Ping() error {
-	if c.conn.IsClosed() {
+	conn := c.connection()
+	if conn == nil || conn.IsClosed() {
 		return amqp.ErrClosed
 	}
 	return nil
 }
-
-func consumeLoop(deliveries <-chan amqp.Delivery, subHandler SubscribeHandler) {
-	for d := range deliveries {
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
-		defer cancel()
-
-		// Invoke the handlerFunc func we passed as parameter.
-		subHandler(ctx, &Message{
-			Delivery: d,
-			Body:     d.Body,
-		})
-	}
-}
@@ -0,0 +1,84 @@
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+func TestNewMessage(t *testing.T) {
+	t.Run("Should expose the priority and the expiration of the delivery", func(t *testing.T) {
+		msg := newMessage(amqp.Delivery{Priority: 9, Expiration: "30000", Body: []byte("body")})
+
+		assert.Equal(t, uint8(9), msg.Priority)
+		assert.Equal(t, 30*time.Second, msg.Expiration)
+		assert.Equal(t, "body", string(msg.Body))
+	})
+	t.Run("Should leave the expiration empty when the delivery has none", func(t *testing.T) {
+		msg := newMessage(amqp.Delivery{})
+
+		assert.Equal(t, uint8(0), msg.Priority)
+		assert.Equal(t, time.Duration(0), msg.Expiration)
+	})
+}
+
+func TestConsumeLoop(t *testing.T) {
+	newConsumer := func(handler SubscribeHandler) *consumer {
+		return &consumer{
+			subHandler:  handler,
+			baseCtx:     context.Background(),
+			timeout:     time.Second,
+			metricAttrs: consumerAttributes("orders", "orders", "consumer"),
+			status:      ConsumerActive,
+			stopping:    make(chan struct{}),
+			done:        make(chan struct{}),
+		}
+	}
+
+	t.Run("Should not handle the deliveries buffered after the consumer is stopped", func(t *testing.T) {
+		var handled []string
+		var cs *consumer
+		cs = newConsumer(func(ctx context.Context, msg *Message) error {
+			handled = append(handled, string(msg.Body))
+			cs.stop()
+			return nil
+		})
+
+		deliveries := make(chan amqp.Delivery, 3)
+		deliveries <- amqp.Delivery{Body: []byte("1")}
+		deliveries <- amqp.Delivery{Body: []byte("2")}
+		deliveries <- amqp.Delivery{Body: []byte("3")}
+		close(deliveries)
+
+		cs.consumeLoop(deliveries)
+
+		assert.Equal(t, []string{"1"}, handled)
+		assert.Equal(t, ConsumerStopped, cs.status)
+	})
+}
+
+func TestConsumerAttributes(t *testing.T) {
+	attrs := func(opt metric.MeasurementOption) attribute.Set {
+		return metric.NewAddConfig([]metric.AddOption{opt}).Attributes()
+	}
+
+	t.Run("Should label the metrics with the configured consumer name", func(t *testing.T) {
+		set := attrs(consumerAttributes("orders", "orders", "orders-worker"))
+		name, ok := set.Value("messaging.consumer.name")
+
+		assert.True(t, ok)
+		assert.Equal(t, "orders-worker", name.AsString())
+	})
+
+	t.Run("Should not label the metrics with the consumer name when it is not configured", func(t *testing.T) {
+		set := attrs(consumerAttributes("orders", "orders", ""))
+
+		assert.False(t, set.HasValue("messaging.consumer.name"))
+		assert.Equal(t, 2, set.Len())
+	})
+}
@@ -0,0 +1,223 @@
+package rabbitmq
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+const (
+	// retryQueueSuffix is appended to the consumer queue name, followed by the retry level, to name the retry queues.
+	retryQueueSuffix = ".retry."
+	// parkingLotQueueSuffix is appended to the consumer queue name to name the parking-lot queue.
+	parkingLotQueueSuffix = ".parking-lot"
+	// xDeathHeader is the header set by the broker every time a message is dead-lettered.
+	xDeathHeader = "x-death"
+)
+
+// RetryConfig represents the configuration of the retry topology of a consumer.
+//
+// For each delay a retry queue named "<queue>.retry.<level>" is declared, dead-lettering expired messages back to the main queue.
+// When the handler returns an error, the message is moved to the retry queue of its current attempt with the delay as its expiration,
+// so it is consumed again after increasing delays. The delay is also the message TTL of the retry queue,
+// so a message moved without expiration, like by an older version of the consumer, still goes back to the main queue.
+// The failed message is acknowledged only after the broker confirms the moved one, and requeued if it does not.
+// When every delay has been used, the message is moved to the "<queue>.parking-lot" queue and is not retried again.
+//
+// Ex.:
+//
+//	RetryConfig{
+//		Delays: []time.Duration{time.Second, 10 * time.Second, time.Minute},
+//	}
+//
+// retries a failed message after 1 second, 10 seconds and 1 minute, and then parks it.
+type RetryConfig struct {
+	Delays []time.Duration
+}
+
+// retryTopology represents the retry and parking-lot queues declared for a consumer queue.
+type retryTopology struct {
+	retryQueues []string
+	delays      []time.Duration
+	parkingLot  string
+}
+
+// declareRetryTopology declares the retry queues and the parking-lot queue of the given queue,
+// or just checks that they exist in passive mode.
+func declareRetryTopology(ch *amqp.Channel, queueName string, rc RetryConfig, passive bool) (*retryTopology, error) {
+	if queueName == "" {
+		return nil, errors.New("Retry topology requires a named queue")
+	}
+
+	declare := ch.QueueDeclare
+	if passive {
+		declare = ch.QueueDeclarePassive
+	}
+
+	rt := newRetryTopology(queueName, rc)
+	for i, name := range rt.retryQueues {
+		_, err := declare(
+			name,  // name of the queue
+			true,  // durable
+			false, // delete when usused
+			false, // exclusive
+			false, // noWait
+			amqp.Table{
+				"x-message-ttl":             rc.Delays[i].Milliseconds(),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": queueName,
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to register the retry Queue %s: %s", name, err)
+		}
+	}
+
+	_, err := declare(
+		rt.parkingLot, // name of the queue
+		true,          // durable
+		false,         // delete when usused
+		false,         // exclusive
+		false,         // noWait
+		nil,           // arguments
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to register the parking-lot Queue %s: %s", rt.parkingLot, err)
+	}
+
+	return rt, nil
+}
+
+// newRetryTopology returns the names of the retry and parking-lot queues of the given queue.
+func newRetryTopology(queueName string, rc RetryConfig) *retryTopology {
+	rt := &retryTopology{
+		delays:     rc.Delays,
+		parkingLot: queueName + parkingLotQueueSuffix,
+	}
+	for i := range rc.Delays {
+		rt.retryQueues = append(rt.retryQueues, fmt.Sprintf("%s%s%d", queueName, retryQueueSuffix, i+1))
+	}
+
+	return rt
+}
+
+// attempts returns how many times the delivery went through the retry queues,
+// counting the x-death entries the broker added when the message expired in each of them.
+func (rt *retryTopology) attempts(d amqp.Delivery) (n int) {
+	deaths, ok := d.Headers[xDeathHeader].([]interface{})
+	if !ok {
+		return
+	}
+
+	for _, death := range deaths {
+		table, ok := death.(amqp.Table)
+		if !ok {
+			continue
+		}
+
+		queue, _ := table["queue"].(string)
+		if !rt.isRetryQueue(queue) {
+			continue
+		}
+
+		count, _ := table["count"].(int64)
+		n += int(count)
+	}
+
+	return
+}
+
+func (rt *retryTopology) isRetryQueue(name string) bool {
+	for _, q := range rt.retryQueues {
+		if q == name {
+			return true
+		}
+	}
+	return false
+}
+
+// destination returns the queue that a failed delivery should be moved to,
+// the retry queue of its next attempt or the parking-lot queue when the retries are exhausted.
+func (rt *retryTopology) destination(d amqp.Delivery) string {
+	attempt := rt.attempts(d)
+	if attempt < len(rt.retryQueues) {
+		return rt.retryQueues[attempt]
+	}
+
+	return rt.parkingLot
+}
+
+// next returns the queue a failed delivery should be moved to and the message to publish in it,
+// that expires after the delay of the retry queue, or does not expire in the parking-lot queue.
+func (rt *retryTopology) next(d amqp.Delivery) (string, amqp.Publishing) {
+	msg := republishing(d)
+
+	attempt := rt.attempts(d)
+	if attempt < len(rt.retryQueues) {
+		msg.Expiration = Expiration(rt.delays[attempt])
+		return rt.retryQueues[attempt], msg
+	}
+
+	return rt.parkingLot, msg
+}
+
+// retry moves a failed delivery to its next retry queue or to the parking-lot queue.
+func (rt *retryTopology) retry(ch *amqp.Channel, confirms <-chan amqp.Confirmation, d amqp.Delivery) {
+	queue, msg := rt.next(d)
+	moveTo(ch, confirms, d, queue, msg)
+}
+
+// park moves a delivery straight to the parking-lot queue, without retrying it.
+func (rt *retryTopology) park(ch *amqp.Channel, confirms <-chan amqp.Confirmation, d amqp.Delivery) {
+	moveTo(ch, confirms, d, rt.parkingLot, republishing(d))
+}
+
+// moveTo publishes the message in the given queue through the consumer channel, in confirm mode,
+// acknowledging the delivery on the consumer queue once the broker confirms the message.
+// If the message cannot be published or is not confirmed, the delivery is requeued in the consumer queue,
+// and if the channel is closed before the confirmation, the broker requeues it.
+func moveTo(ch *amqp.Channel, confirms <-chan amqp.Confirmation, d amqp.Delivery, queue string, msg amqp.Publishing) {
+	// publishing through the default exchange routes the message directly to the queue
+	err := ch.Publish("", queue, false, false, msg)
+	if err != nil {
+		log.Printf("Failed to move message to queue %s: %s", queue, err)
+		d.Nack(false, true)
+		return
+	}
+
+	confirmation, ok := <-confirms
+	if !ok {
+		log.Printf("Failed to move message to queue %s: %s", queue, amqp.ErrClosed)
+		return
+	}
+	if !confirmation.Ack {
+		log.Printf("Failed to move message to queue %s: %s", queue, ErrNotConfirmed)
+		d.Nack(false, true)
+		return
+	}
+
+	d.Ack(false)
+}
+
+// republishing copies the content and properties of a delivery to a new publishing.
+// The expiration and the user id are not copied, since the message should not expire in the new queue
+// and the user id must match the one of the connection.
+func republishing(d amqp.Delivery) amqp.Publishing {
+	return amqp.Publishing{
+		Headers:         d.Headers,
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		DeliveryMode:    amqp.Persistent,
+		Priority:        d.Priority,
+		CorrelationId:   d.CorrelationId,
+		ReplyTo:         d.ReplyTo,
+		MessageId:       d.MessageId,
+		Timestamp:       d.Timestamp,
+		Type:            d.Type,
+		AppId:           d.AppId,
+		Body:            d.Body,
+	}
+}
@@ -0,0 +1,91 @@
+package rabbitmq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryTopology(t *testing.T) {
+	rt := newRetryTopology("orders", RetryConfig{
+		Delays: []time.Duration{time.Second, time.Minute},
+	})
+
+	death := func(queue string, count int64) amqp.Table {
+		return amqp.Table{"queue": queue, "reason": "expired", "count": count}
+	}
+
+	t.Run("Should name the retry and parking-lot queues after the consumer queue", func(t *testing.T) {
+		assert.Equal(t, []string{"orders.retry.1", "orders.retry.2"}, rt.retryQueues)
+		assert.Equal(t, "orders.parking-lot", rt.parkingLot)
+	})
+	t.Run("Should send a message without x-death to the first retry queue", func(t *testing.T) {
+		d := amqp.Delivery{}
+
+		assert.Equal(t, 0, rt.attempts(d))
+		assert.Equal(t, "orders.retry.1", rt.destination(d))
+	})
+	t.Run("Should only count deaths from the retry queues", func(t *testing.T) {
+		d := amqp.Delivery{
+			Headers: amqp.Table{
+				xDeathHeader: []interface{}{
+					death("orders.retry.1", 1),
+					death("other-queue", 3),
+				},
+			},
+		}
+
+		assert.Equal(t, 1, rt.attempts(d))
+		assert.Equal(t, "orders.retry.2", rt.destination(d))
+	})
+	t.Run("Should send the message to the parking-lot when the retries are exhausted", func(t *testing.T) {
+		d := amqp.Delivery{
+			Headers: amqp.Table{
+				xDeathHeader: []interface{}{
+					death("orders.retry.2", 1),
+					death("orders.retry.1", 1),
+				},
+			},
+		}
+
+		assert.Equal(t, 2, rt.attempts(d))
+		assert.Equal(t, "orders.parking-lot", rt.destination(d))
+	})
+	t.Run("Should move the message to the retry queue expiring after its delay", func(t *testing.T) {
+		d := amqp.Delivery{
+			Body:       []byte("body"),
+			Expiration: "5",
+			Headers: amqp.Table{
+				xDeathHeader: []interface{}{death("orders.retry.1", 1)},
+			},
+		}
+
+		queue, msg := rt.next(d)
+
+		assert.Equal(t, "orders.retry.2", queue)
+		assert.Equal(t, "60000", msg.Expiration)
+		assert.Equal(t, []byte("body"), msg.Body)
+	})
+	t.Run("Should move the message to the parking-lot without expiration", func(t *testing.T) {
+		d := amqp.Delivery{
+			Expiration: "5",
+			Headers: amqp.Table{
+				xDeathHeader: []interface{}{death("orders.retry.2", 1), death("orders.retry.1", 1)},
+			},
+		}
+
+		queue, msg := rt.next(d)
+
+		assert.Equal(t, "orders.parking-lot", queue)
+		assert.Empty(t, msg.Expiration)
+	})
+	t.Run("Should ignore a malformed x-death header", func(t *testing.T) {
+		d := amqp.Delivery{
+			Headers: amqp.Table{xDeathHeader: "invalid"},
+		}
+
+		assert.Equal(t, 0, rt.attempts(d))
+	})
+}
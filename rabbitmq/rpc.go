@@ -0,0 +1,243 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/delivery-much/dm-go/logger"
+	"github.com/delivery-much/dm-go/optel"
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+const (
+	// directReplyTo is the pseudo-queue of the RabbitMQ direct reply-to feature,
+	// that delivers the replies to the channel that published the request without declaring a reply queue.
+	directReplyTo = "amq.rabbitmq.reply-to"
+
+	// RPCErrorHeader is the reply header that carries the error returned by the handler of an RPCServer.
+	RPCErrorHeader = "x-rpc-error"
+)
+
+var (
+	// ErrNoReplyTo is returned by an RPCServer when the request has no ReplyTo to publish the reply.
+	ErrNoReplyTo = errors.New("request has no reply-to")
+	// ErrRemote is returned by Call, wrapping the error message, when the handler of the RPCServer fails.
+	ErrRemote = errors.New("rpc handler failed")
+)
+
+// RPCHandlerFunc signature of func to handle a request and return the reply to be published back to the caller
+type RPCHandlerFunc func(ctx context.Context, msg *Message) (amqp.Publishing, error)
+
+// rpcChannel represents the channel of the client used to publish the requests of Call and to consume their replies,
+// reopened when it is closed.
+type rpcChannel struct {
+	mu sync.Mutex
+	// conn is the connection the channel was opened in
+	conn *amqp.Connection
+	ch   *amqp.Channel
+	// pending maps the correlation id of the requests awaiting a reply to the channel that receives it
+	pending map[string]chan amqp.Delivery
+}
+
+// Call publishes a request in the exchange with the given routing key and waits for its reply,
+// returning ErrRemote when the RPCServer handler fails, or the context error if the context is done before the reply.
+// The replies are received through the direct reply-to of RabbitMQ, so no reply queue is declared.
+//
+// The CorrelationId is generated when empty, and when the context has a deadline
+// the request expires with it, so it is not handled after the caller gave up.
+//
+// Ex.:
+//
+//	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+//	defer cancel()
+//
+//	reply, err := client.Call(ctx, "", "pricing.quote", amqp.Publishing{
+//		ContentType: "application/json",
+//		Body:        body,
+//	})
+func (c *Client) Call(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) (*Message, error) {
+	ctx, end := optel.StartTrackProducer(ctx, spanName(exchange, "call"), publishingAttributes(exchange, routingKey, msg)...)
+	defer end()
+
+	msg = request(ctx, msg)
+
+	reply, err := c.rpc.call(ctx, c.connection(), exchange, routingKey, msg)
+	if err != nil {
+		recordSpanError(ctx, err)
+		return nil, err
+	}
+
+	return reply, nil
+}
+
+// request returns the message with the reply properties of a request and the context injected in its headers.
+func request(ctx context.Context, msg amqp.Publishing) amqp.Publishing {
+	msg.Headers = injectContext(ctx, msg.Headers)
+	msg.ReplyTo = directReplyTo
+	if msg.CorrelationId == "" {
+		msg.CorrelationId = uuid.New().String()
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && msg.Expiration == "" {
+		msg.Expiration = Expiration(time.Until(deadline))
+	}
+
+	return msg
+}
+
+// reply returns the Message of a reply, or ErrRemote if it carries the error of the RPCServer handler.
+func reply(d amqp.Delivery) (*Message, error) {
+	if remote, ok := d.Headers[RPCErrorHeader].(string); ok {
+		return nil, fmt.Errorf("%w: %s", ErrRemote, remote)
+	}
+
+	return newMessage(d), nil
+}
+
+func (rc *rpcChannel) call(ctx context.Context, conn *amqp.Connection, exchange, routingKey string, msg amqp.Publishing) (*Message, error) {
+	replies := make(chan amqp.Delivery, 1)
+
+	rc.mu.Lock()
+	ch, err := rc.channel(conn)
+	if err != nil {
+		rc.mu.Unlock()
+		return nil, err
+	}
+	rc.pending[msg.CorrelationId] = replies
+	rc.mu.Unlock()
+
+	defer func() {
+		rc.mu.Lock()
+		defer rc.mu.Unlock()
+		delete(rc.pending, msg.CorrelationId)
+	}()
+
+	start := time.Now()
+	err = publishOn(ch, exchange, routingKey, msg)
+	getMetrics().recordPublish(ctx, exchange, start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case d, ok := <-replies:
+		if !ok {
+			return nil, fmt.Errorf("Failed to receive the reply: %s", amqp.ErrClosed)
+		}
+		return reply(d)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// channel returns the channel of the calls, opening a new one consuming the direct reply-to if it was not opened yet,
+// if it was closed or if the client reconnected. It must be called holding the lock.
+func (rc *rpcChannel) channel(conn *amqp.Connection) (*amqp.Channel, error) {
+	if rc.ch != nil && rc.conn == conn {
+		return rc.ch, nil
+	}
+
+	ch, err := openChannel(conn)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open a channel: %s", err)
+	}
+
+	// the direct reply-to must be consumed in no-ack mode, in the same channel that publishes the requests
+	replies, err := ch.Consume(
+		directReplyTo, // queue
+		"",            // consumer
+		true,          // auto-ack
+		false,         // exclusive
+		false,         // no-local
+		false,         // no-wait
+		nil,           // args
+	)
+	if err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("Failed to consume the replies: %s", err)
+	}
+
+	rc.conn = conn
+	rc.ch = ch
+	rc.pending = map[string]chan amqp.Delivery{}
+	go rc.dispatch(ch, rc.pending, replies)
+
+	return ch, nil
+}
+
+// dispatch delivers each reply to the call awaiting it, discarding the replies of calls that gave up.
+// When the channel is closed, the pending calls are failed.
+func (rc *rpcChannel) dispatch(ch *amqp.Channel, pending map[string]chan amqp.Delivery, replies <-chan amqp.Delivery) {
+	for d := range replies {
+		rc.mu.Lock()
+		if r, ok := pending[d.CorrelationId]; ok {
+			delete(pending, d.CorrelationId)
+			r <- d
+		}
+		rc.mu.Unlock()
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	for id, r := range pending {
+		delete(pending, id)
+		close(r)
+	}
+	if rc.ch == ch {
+		rc.ch = nil
+	}
+}
+
+// RPCServer returns a SubscribeHandler that invokes the given handler with each request
+// and publishes its reply back to the caller, through the default exchange to the ReplyTo of the request,
+// with the same CorrelationId.
+//
+// When the handler fails, the error is replied to the caller in the RPCErrorHeader and the request is rejected, see Reject,
+// since the caller is not waiting for it anymore. Requests without ReplyTo are logged and rejected.
+// If the reply cannot be published, the error is returned so the request is retried or rejected like any other message.
+//
+// Ex.:
+//
+//	client.Subscribe(ctx, cg, rabbitmq.RPCServer(client, func(ctx context.Context, msg *rabbitmq.Message) (amqp.Publishing, error) {
+//		quote, err := quotes.Get(ctx, msg.Body)
+//		if err != nil {
+//			return amqp.Publishing{}, err
+//		}
+//		return amqp.Publishing{ContentType: "application/json", Body: quote}, nil
+//	}))
+func RPCServer(client RabbitMQ, handler RPCHandlerFunc) SubscribeHandler {
+	return func(ctx context.Context, msg *Message) error {
+		if msg.Delivery.ReplyTo == "" {
+			logger.Errorw(ctx, "Failed to handle request",
+				"error", ErrNoReplyTo.Error(),
+				"exchange", msg.Delivery.Exchange,
+				"routing_key", msg.Delivery.RoutingKey,
+				"message_id", msg.Delivery.MessageId,
+			)
+			return Reject(ErrNoReplyTo)
+		}
+
+		response, handlerErr := handler(ctx, msg)
+		if handlerErr != nil {
+			response = amqp.Publishing{
+				Headers: amqp.Table{RPCErrorHeader: handlerErr.Error()},
+			}
+		}
+		response.CorrelationId = msg.Delivery.CorrelationId
+
+		err := client.Publish(ctx, "", msg.Delivery.ReplyTo, response)
+		if err != nil {
+			return err
+		}
+
+		if handlerErr != nil {
+			return Reject(handlerErr)
+		}
+
+		return nil
+	}
+}
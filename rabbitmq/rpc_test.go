@@ -0,0 +1,89 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequest(t *testing.T) {
+	t.Run("Should set the reply-to and generate the correlation id", func(t *testing.T) {
+		msg := request(context.Background(), amqp.Publishing{})
+
+		assert.Equal(t, directReplyTo, msg.ReplyTo)
+		assert.NotEmpty(t, msg.CorrelationId)
+		assert.Empty(t, msg.Expiration)
+	})
+	t.Run("Should keep the correlation id and expire the request with the context deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		msg := request(ctx, amqp.Publishing{CorrelationId: "correlation-id"})
+
+		expiration, err := strconv.Atoi(msg.Expiration)
+		assert.Nil(t, err)
+		assert.Equal(t, "correlation-id", msg.CorrelationId)
+		assert.LessOrEqual(t, expiration, 5000)
+		assert.Greater(t, expiration, 4000)
+	})
+}
+
+func TestRPC(t *testing.T) {
+	ctx := context.Background()
+	cg := ConsumerConfig{ExchangeName: "pricing", ExchangeType: "direct", QueueName: "pricing", BindingKey: "quote"}
+
+	t.Run("Should reply the result of the handler to the caller", func(t *testing.T) {
+		f := NewFake()
+		f.Subscribe(ctx, cg, RPCServer(f, func(ctx context.Context, msg *Message) (amqp.Publishing, error) {
+			return amqp.Publishing{Body: append([]byte("quote for "), msg.Body...)}, nil
+		}))
+
+		reply, err := f.Call(ctx, "pricing", "quote", amqp.Publishing{CorrelationId: "correlation-id", Body: []byte("order")})
+
+		assert.Nil(t, err)
+		assert.Equal(t, "quote for order", string(reply.Body))
+		assert.Equal(t, "correlation-id", reply.Delivery.CorrelationId)
+	})
+	t.Run("Should reply the error of the handler to the caller and reject the request", func(t *testing.T) {
+		f := NewFake()
+		f.Subscribe(ctx, cg, RPCServer(f, func(ctx context.Context, msg *Message) (amqp.Publishing, error) {
+			return amqp.Publishing{}, errors.New("product not found")
+		}))
+
+		reply, err := f.Call(ctx, "pricing", "quote", amqp.Publishing{})
+		waitFake(t, f)
+
+		assert.Nil(t, reply)
+		assert.ErrorIs(t, err, ErrRemote)
+		assert.Contains(t, err.Error(), "product not found")
+		assert.Len(t, f.DeadLetters(), 1)
+	})
+	t.Run("Should reject requests without reply-to", func(t *testing.T) {
+		f := NewFake()
+		f.Subscribe(ctx, cg, RPCServer(f, func(ctx context.Context, msg *Message) (amqp.Publishing, error) {
+			return amqp.Publishing{}, nil
+		}))
+
+		f.Publish(ctx, "pricing", "quote", amqp.Publishing{})
+		waitFake(t, f)
+
+		deadLetters := f.DeadLetters()
+		assert.Len(t, deadLetters, 1)
+		assert.ErrorIs(t, deadLetters[0].Err, ErrNoReplyTo)
+	})
+	t.Run("Should return the context error when no reply arrives", func(t *testing.T) {
+		f := NewFake()
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		reply, err := f.Call(ctx, "pricing", "quote", amqp.Publishing{})
+
+		assert.Nil(t, reply)
+		assert.Equal(t, context.DeadlineExceeded, err)
+	})
+}